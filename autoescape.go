@@ -0,0 +1,270 @@
+package zplgfa
+
+import (
+	"fmt"
+	"strings"
+	"text/template/parse"
+	"unicode/utf8"
+)
+
+// valueEscaper is the function RenderTemplate appends to the pipelines of
+// the actions
+const valueEscaper = "_zplValue"
+
+// The values are marked by Unicode noncharacters, which are meant for
+// internal use, until they are escaped by escapeValues
+const (
+	valueStart = "\uFDD0"
+	valueEnd   = "\uFDD1"
+)
+
+// addEscaper appends the valueEscaper to the actions of the tree
+func addEscaper(node parse.Node) {
+	switch n := node.(type) {
+	case *parse.ListNode:
+		if n == nil {
+			return
+		}
+		for _, child := range n.Nodes {
+			addEscaper(child)
+		}
+	case *parse.ActionNode:
+		// declarations don't print anything
+		if len(n.Pipe.Decl) == 0 {
+			cmd := &parse.CommandNode{NodeType: parse.NodeCommand, Pos: n.Pos}
+			cmd.Args = []parse.Node{parse.NewIdentifier(valueEscaper).SetPos(n.Pos)}
+			n.Pipe.Cmds = append(n.Pipe.Cmds, cmd)
+		}
+	case *parse.IfNode:
+		addEscaper(n.List)
+		addEscaper(n.ElseList)
+	case *parse.RangeNode:
+		addEscaper(n.List)
+		addEscaper(n.ElseList)
+	case *parse.WithNode:
+		addEscaper(n.List)
+		addEscaper(n.ElseList)
+	}
+}
+
+// markedZPL is the output of the functions of RenderTemplate, trusted ZPL
+// with the values in it already marked
+type markedZPL string
+
+// markers removes the value markers from strings
+var markers = strings.NewReplacer(valueStart, "", valueEnd, "")
+
+// escapeValue marks the values inserted by a template, except trusted ZPL
+func escapeValue(v interface{}) markedZPL {
+	switch zpl := v.(type) {
+	case markedZPL:
+		return zpl
+	case ZPL:
+		return markedZPL(markers.Replace(string(zpl)))
+	}
+	return markedZPL(markValue(v))
+}
+
+// markValue marks v for escapeValues
+func markValue(v interface{}) string {
+	return valueStart + markers.Replace(fmt.Sprint(v)) + valueEnd
+}
+
+// escapeValues replaces the values marked by markValue with their escaped
+// form, see RenderTemplate
+func escapeValues(zpl string) (string, error) {
+	e := &valueEscaping{caret: '^', tilde: '~', codePage: "0", hexAt: -1}
+	for {
+		i := strings.Index(zpl, valueStart)
+		if i < 0 {
+			e.literal(zpl)
+			break
+		}
+		e.literal(zpl[:i])
+		zpl = zpl[i+len(valueStart):]
+		j := strings.Index(zpl, valueEnd)
+		if j < 0 {
+			return "", fmt.Errorf("template: unterminated value")
+		}
+		if err := e.value(zpl[:j]); err != nil {
+			return "", err
+		}
+		zpl = zpl[j+len(valueEnd):]
+	}
+	if e.field != nil {
+		// ValidateZPL reports the unclosed field
+		e.out = append(e.out, e.field.join(0, "")...)
+	}
+	return string(e.out), nil
+}
+
+// valueEscaping follows the commands around the values for escapeValues
+type valueEscaping struct {
+	out          []byte
+	caret, tilde byte
+	// codePage is the parameter of the last ^CI
+	codePage string
+	// hexAt is the offset of a ^FH in out which applies to the next field,
+	// -1 if there's none
+	hexAt     int
+	indicator byte
+	// field collects the field data up to its ^FS, nil outside of fields
+	field *fieldData
+}
+
+// fieldData is the data of a field with the values in it
+type fieldData struct {
+	// start is the offset of the ^FH or ^FD of the field in out
+	start int
+	// indicator is the hex indicator of its ^FH, 0 without ^FH
+	indicator byte
+	parts     []string
+	values    []bool
+}
+
+// join returns the field data, hex escaping the prefixes in the values with
+// indicator unless it's 0
+func (f *fieldData) join(indicator byte, prefixes string) string {
+	var sb strings.Builder
+	for i, part := range f.parts {
+		if indicator == 0 || !f.values[i] && indicator == f.indicator {
+			// the template takes care of the escaping
+			sb.WriteString(part)
+			continue
+		}
+		for j := 0; j < len(part); j++ {
+			c := part[j]
+			if c == indicator || f.values[i] && strings.IndexByte(prefixes, c) >= 0 {
+				fmt.Fprintf(&sb, "%c%02X", indicator, c)
+			} else {
+				sb.WriteByte(c)
+			}
+		}
+	}
+	return sb.String()
+}
+
+func (e *valueEscaping) write(s string, value bool) {
+	if e.field == nil {
+		e.out = append(e.out, s...)
+		return
+	}
+	e.field.parts = append(e.field.parts, s)
+	e.field.values = append(e.field.values, value)
+}
+
+// literal follows the commands of the template text s
+func (e *valueEscaping) literal(s string) {
+	for s != "" {
+		i := strings.IndexAny(s, string([]byte{e.caret, e.tilde}))
+		if i < 0 {
+			e.write(s, false)
+			return
+		}
+		e.write(s[:i], false)
+		s = s[i:]
+		s = s[e.command(s):]
+	}
+}
+
+// command follows the command at the start of s and returns its length
+func (e *valueEscaping) command(s string) int {
+	if len(s) < 3 {
+		e.write(s, false)
+		return len(s)
+	}
+	name := strings.ToUpper(s[1:3])
+	if name == "CC" || name == "CT" {
+		n := 3
+		if len(s) > 3 {
+			if name == "CC" {
+				e.caret = s[3]
+			} else {
+				e.tilde = s[3]
+			}
+			n++
+		}
+		e.write(s[:n], false)
+		return n
+	}
+	isCaret := s[0] == e.caret
+	if e.field != nil {
+		if isCaret && name == "FS" {
+			restore := e.closeField()
+			e.out = append(append(e.out, s[:3]...), restore...)
+			return 3
+		}
+		e.write(s[:3], false)
+		return 3
+	}
+	switch {
+	case isCaret && name == "FH":
+		e.hexAt, e.indicator = len(e.out), '_'
+		if len(s) > 3 && s[3] > ' ' && s[3] != e.caret && s[3] != e.tilde {
+			e.indicator = s[3]
+		}
+	case isCaret && (name == "FD" || name == "FV"):
+		e.field = &fieldData{start: len(e.out)}
+		if e.hexAt >= 0 {
+			e.field.start, e.field.indicator = e.hexAt, e.indicator
+		}
+		e.hexAt = -1
+		e.out = append(e.out, s[:3]...)
+		return 3
+	case isCaret && name == "CI":
+		n := 3
+		for n < len(s) && s[n] >= '0' && s[n] <= '9' {
+			n++
+		}
+		e.codePage = s[3:n]
+		fallthrough
+	default:
+		e.hexAt = -1
+	}
+	e.write(s[:3], false)
+	return 3
+}
+
+// value handles a value inserted by the template
+func (e *valueEscaping) value(s string) error {
+	if e.field == nil && strings.IndexAny(s, string([]byte{e.caret, e.tilde})) >= 0 {
+		return fmt.Errorf("template: value %q with command prefixes outside of field data", s)
+	}
+	e.write(s, true)
+	return nil
+}
+
+// closeField writes the field data, escaped as needed by its values, and
+// returns the commands to follow its ^FS
+func (e *valueEscaping) closeField() string {
+	f := e.field
+	e.field = nil
+	commandPrefixes := string([]byte{e.caret, e.tilde})
+	prefixes, unicode := false, false
+	for i, part := range f.parts {
+		if !f.values[i] {
+			continue
+		}
+		if strings.IndexAny(part, commandPrefixes) >= 0 {
+			prefixes = true
+		}
+		for _, r := range part {
+			if r >= utf8.RuneSelf {
+				unicode = true
+			}
+		}
+	}
+
+	var before, after string
+	indicator := f.indicator
+	if prefixes && indicator == 0 {
+		before, indicator = string(e.caret)+"FH", '_'
+	}
+	if unicode && e.codePage != "28" {
+		before = string(e.caret) + "CI28" + before
+		after = string(e.caret) + "CI" + e.codePage
+	}
+	head := append([]byte(before), e.out[f.start:]...)
+	e.out = append(append(e.out[:f.start], head...), f.join(indicator, commandPrefixes)...)
+	return after
+}
@@ -0,0 +1,46 @@
+package zplgfa
+
+import "testing"
+
+func Test_RenderTemplateEscaping(t *testing.T) {
+	data := map[string]interface{}{
+		"Name":  "Jane ^Doe~",
+		"City":  "Zürich",
+		"Items": []string{"a_b", "c^d"},
+		"Raw":   ZPL("^FO0,0^GB10,10,1^FS"),
+		// the noncharacters RenderTemplate marks the values with
+		"Marker":    "a\uFDD1b",
+		"RawMarker": ZPL("^FO0,0\uFDD0^FS"),
+	}
+	tests := []struct {
+		tmpl string
+		want string
+	}{
+		{"^XA^FO0,0^FD{{.Name}}^FS^XZ", "^XA^FO0,0^FH^FDJane _5EDoe_7E^FS^XZ"},
+		{"^XA^FO0,0^FDName_{{.Name}}^FS^XZ", "^XA^FO0,0^FH^FDName_5FJane _5EDoe_7E^FS^XZ"},
+		{"^XA^FO0,0^FH\\^FD\\5E{{.Name}}^FS^XZ", "^XA^FO0,0^FH\\^FD\\5EJane \\5EDoe\\7E^FS^XZ"},
+		{"^XA^FO0,0^FD{{.City}}^FS^FO0,20^FDBern^FS^XZ", "^XA^FO0,0^CI28^FDZürich^FS^CI0^FO0,20^FDBern^FS^XZ"},
+		{"^XA^CI27^FO0,0^FD{{.City}}^FS^XZ", "^XA^CI27^FO0,0^CI28^FDZürich^FS^CI27^XZ"},
+		{"^XA^CI28^FO0,0^FD{{.City}}^FS^XZ", "^XA^CI28^FO0,0^FDZürich^FS^XZ"},
+		{"^XA{{range .Items}}^FD{{.}}^FS{{end}}^XZ", "^XA^FDa_b^FS^FH^FDc_5Ed^FS^XZ"},
+		{"^XA^CC|{{$n := .Name}}|FD{{$n}}|FS|CC^^XZ", "^XA^CC||FH|FDJane ^Doe_7E|FS|CC^^XZ"},
+		{"^XA{{.Raw}}^XZ", "^XA^FO0,0^GB10,10,1^FS^XZ"},
+		{"^XA^FO{{mm 5}},0{{zplEscape .Name}}^FS^XZ", "^XA^FO40,0^FH^FDJane _5EDoe_7E^FS^XZ"},
+		{"^XA^FO0,0{{with code128 .Name 100}}{{.}}{{end}}^XZ", "^XA^FO0,0^BCN,100,Y,N,N^FH^FDJane _5EDoe_7E^FS^XZ"},
+		{"^XA^FD{{.Marker}}^FS{{.RawMarker}}^XZ", "^XA^FDab^FS^FO0,0^FS^XZ"},
+	}
+	for _, tt := range tests {
+		got, err := RenderTemplate(tt.tmpl, data, 203)
+		if err != nil || got != tt.want {
+			t.Errorf("RenderTemplate(%q) = %q, %v, want %q", tt.tmpl, got, err, tt.want)
+		}
+	}
+
+	// a ^ outside of field data can't be escaped
+	if _, err := RenderTemplate("^XA^FO{{.Name}}^FS^XZ", data, 203); err == nil {
+		t.Error("expected an error for a value with prefixes outside of field data")
+	}
+	if _, err := RenderTemplate("^XA^FDa\uFDD0b^FS^XZ", data, 203); err == nil {
+		t.Error("expected an error for a template with a value marker")
+	}
+}
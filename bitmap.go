@@ -0,0 +1,61 @@
+package zplgfa
+
+import (
+	"image"
+	"image/color"
+)
+
+// bitmap is a black and white copy of an image, thresholded the same way
+// ConvertToGraphicField does. Its origin is always at 0,0.
+type bitmap struct {
+	width, height int
+	pix           []bool
+}
+
+func newBitmap(width, height int) *bitmap {
+	return &bitmap{width: width, height: height, pix: make([]bool, width*height)}
+}
+
+// bitmapFromImage thresholds img into a new bitmap
+func bitmapFromImage(img image.Image) *bitmap {
+	b := img.Bounds()
+	bm := newBitmap(b.Dx(), b.Dy())
+	for y := 0; y < bm.height; y++ {
+		for x := 0; x < bm.width; x++ {
+			bm.pix[y*bm.width+x] = isBlack(img.At(b.Min.X+x, b.Min.Y+y).RGBA())
+		}
+	}
+	return bm
+}
+
+func (bm *bitmap) black(x, y int) bool {
+	if x < 0 || y < 0 || x >= bm.width || y >= bm.height {
+		return false
+	}
+	return bm.pix[y*bm.width+x]
+}
+
+func (bm *bitmap) set(x, y int, black bool) {
+	bm.pix[y*bm.width+x] = black
+}
+
+func (bm *bitmap) clone() *bitmap {
+	c := newBitmap(bm.width, bm.height)
+	copy(c.pix, bm.pix)
+	return c
+}
+
+func (bm *bitmap) ColorModel() color.Model {
+	return color.GrayModel
+}
+
+func (bm *bitmap) Bounds() image.Rectangle {
+	return image.Rect(0, 0, bm.width, bm.height)
+}
+
+func (bm *bitmap) At(x, y int) color.Color {
+	if bm.black(x, y) {
+		return color.Black
+	}
+	return color.White
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/PaackEng/zplgfa/printer"
+)
+
+// discoverCmd runs the discover subcommand, which lists the printers on the
+// local network found via the discovery broadcast or a subnet scan
+func discoverCmd(args []string) error {
+	var subnetFlag string
+	var networkPortFlag string
+	var timeoutFlag time.Duration
+
+	flags := flag.NewFlagSet("discover", flag.ExitOnError)
+	flags.StringVar(&subnetFlag, "subnet", "", "scan the subnet (e.g. 192.168.178.0/24) instead of broadcasting")
+	flags.StringVar(&networkPortFlag, "port", "9100", "network port of the printers")
+	flags.DurationVar(&timeoutFlag, "timeout", printer.DefaultDiscoveryTimeout, "time to wait for responses (per host when scanning)")
+	flags.Parse(args)
+
+	var found []printer.DiscoveredPrinter
+	var err error
+	if subnetFlag != "" {
+		found, err = printer.Scan(context.Background(), subnetFlag, networkPortFlag, timeoutFlag)
+	} else {
+		ctx, cancel := context.WithTimeout(context.Background(), timeoutFlag)
+		defer cancel()
+		found, err = printer.Discover(ctx)
+	}
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(w, "IP\tMODEL\tSERIAL\tNAME")
+	for _, d := range found {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", d.IP, d.Model, d.Serial, d.Name)
+	}
+	return w.Flush()
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"image/png"
+	"log"
+	"net"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/PaackEng/zplgfa"
+)
+
+// maxFormatSize limits the size of a single format received by the emulator
+const maxFormatSize = 64 << 20
+
+// emulateCmd runs the emulate subcommand, a virtual printer which renders
+// every received format to a PNG file next to its raw ZPL
+func emulateCmd(args []string) error {
+	var networkPortFlag string
+	var outFlag string
+	var widthFlag int
+	var heightFlag int
+
+	flags := flag.NewFlagSet("emulate", flag.ExitOnError)
+	flags.StringVar(&networkPortFlag, "port", "9100", "network port to listen on")
+	flags.StringVar(&outFlag, "out", ".", "directory to save the labels to")
+	flags.IntVar(&widthFlag, "width", 812, "label width in dots, unless set by ^PW")
+	flags.IntVar(&heightFlag, "height", 1218, "label height in dots, unless set by ^LL")
+	flags.Parse(args)
+
+	if err := os.MkdirAll(outFlag, 0o755); err != nil {
+		return err
+	}
+	l, err := net.Listen("tcp", ":"+networkPortFlag)
+	if err != nil {
+		return err
+	}
+	log.Printf("emulating a printer on %s, saving labels to %s\n", l.Addr(), outFlag)
+
+	var mu sync.Mutex
+	var count int
+	for {
+		c, err := l.Accept()
+		if err != nil {
+			return err
+		}
+		go func() {
+			defer c.Close()
+			scanner := bufio.NewScanner(c)
+			scanner.Buffer(nil, maxFormatSize)
+			scanner.Split(zplgfa.ScanFormats)
+			for scanner.Scan() {
+				mu.Lock()
+				count++
+				name := filepath.Join(outFlag, fmt.Sprintf("label-%04d", count))
+				mu.Unlock()
+				if err := saveLabel(name, scanner.Bytes(), widthFlag, heightFlag); err != nil {
+					log.Printf("Warning: could not save %s, %s\n", name, err)
+					continue
+				}
+				log.Printf("saved %s.png\n", name)
+			}
+			if err := scanner.Err(); err != nil {
+				log.Printf("Warning: could not read from %s, %s\n", c.RemoteAddr(), err)
+			}
+		}()
+	}
+}
+
+// saveLabel renders the format to name.png and saves it as name.zpl. Nothing
+// is saved if the format can't be rendered.
+func saveLabel(name string, format []byte, width, height int) error {
+	img, err := renderLabel(format, width, height)
+	var unsupported *zplgfa.UnsupportedError
+	if errors.As(err, &unsupported) {
+		log.Printf("Warning: %s.png is incomplete, %s\n", name, err)
+	} else if err != nil {
+		return err
+	}
+	if err := os.WriteFile(name+".zpl", format, 0o644); err != nil {
+		return err
+	}
+	file, err := os.Create(name + ".png")
+	if err != nil {
+		return err
+	}
+	if err := png.Encode(file, img); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
+
+// renderLabel renders the format, a panic while rendering only fails this
+// format instead of the emulator
+func renderLabel(format []byte, width, height int) (img image.Image, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("rendering failed: %v", r)
+		}
+	}()
+	return zplgfa.RenderZPL(string(format), width, height)
+}
@@ -4,6 +4,11 @@ import (
 	"image"
 	"image/color"
 	"math"
+	"strings"
+
+	"github.com/nfnt/resize"
+
+	"github.com/PaackEng/zplgfa"
 )
 
 type imageSet interface {
@@ -47,3 +52,22 @@ func editImageMonochrome(img image.Image) image.Image {
 	}
 	return img
 }
+
+func resizeMitchell(img image.Image, width, height int) image.Image {
+	return resize.Resize(uint(width), uint(height), img, resize.MitchellNetravali)
+}
+
+func resizeImage(img image.Image, scale float64, resampling string) image.Image {
+	b := img.Bounds()
+	width := int(float64(b.Dx()) * scale)
+	height := int(float64(b.Dy()) * scale)
+
+	switch strings.ToLower(resampling) {
+	case "nearest":
+		return zplgfa.ResizeNearest(img, width, height)
+	case "barcode":
+		return zplgfa.ResizeBarcodeSafe(img, scale, resizeMitchell)
+	default:
+		return resizeMitchell(img, width, height)
+	}
+}
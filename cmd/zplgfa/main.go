@@ -1,6 +1,8 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"image"
@@ -8,53 +10,90 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"log"
+	"net"
 	"os"
 	"strings"
+	"time"
 
 	"github.com/anthonynsimon/bild/blur"
 	"github.com/anthonynsimon/bild/effect"
 	"github.com/anthonynsimon/bild/segment"
-	"github.com/nfnt/resize"
 
-	"github.com/ramirezalbert3/zplgfa"
+	"github.com/PaackEng/zplgfa"
+	"github.com/PaackEng/zplgfa/printer"
 )
 
-func specialCmds(zebraCmdFlag, networkIpFlag, networkPortFlag string) bool {
+// printerAddr returns the address of the printer at host, an IPv6 address
+// may be in brackets. The port is added unless host has one.
+func printerAddr(host, port string) string {
+	host = strings.TrimSpace(host)
+	if _, _, err := net.SplitHostPort(host); err == nil {
+		return host
+	}
+	return net.JoinHostPort(strings.Trim(host, "[]"), port)
+}
+
+func specialCmds(zebraCmdFlag string, zebra *printer.Printer) bool {
 	var cmdSent bool
-	if networkIpFlag == "" {
+	if zebra == nil {
 		return cmdSent
 	}
+	ctx := context.Background()
 	if strings.Contains(zebraCmdFlag, "cancel") {
-		if err := sendCancelCmdToZebra(networkIpFlag, networkPortFlag); err == nil {
+		if err := zebra.Cancel(ctx); err == nil {
 			cmdSent = true
 		}
 	}
 	if strings.Contains(zebraCmdFlag, "calib") {
-		if err := sendCalibCmdToZebra(networkIpFlag, networkPortFlag); err == nil {
+		if err := zebra.Calibrate(ctx); err == nil {
 			cmdSent = true
 		}
 	}
 	if strings.Contains(zebraCmdFlag, "feed") {
-		if err := sendFeedCmdToZebra(networkIpFlag, networkPortFlag); err == nil {
+		if err := zebra.Feed(ctx); err == nil {
 			cmdSent = true
 		}
 	}
 	if strings.Contains(zebraCmdFlag, "info") {
-		info, err := getInfoFromZebra(networkIpFlag, networkPortFlag)
+		info, err := zebra.Info(ctx)
 		if err == nil {
 			fmt.Println(info)
 			cmdSent = true
 		}
 	}
+	if strings.Contains(zebraCmdFlag, "status") {
+		status, err := zebra.Status(ctx)
+		if err == nil {
+			fmt.Printf("%+v\n", *status)
+			if problems := status.Problems(); len(problems) > 0 {
+				fmt.Printf("problems: %s\n", strings.Join(problems, ", "))
+			}
+			cmdSent = true
+		}
+	}
+	if strings.Contains(zebraCmdFlag, "ident") {
+		id, err := zebra.Identify(ctx)
+		if err == nil {
+			fmt.Printf("%+v (%d dpi)\n", *id, id.DPI())
+			cmdSent = true
+		}
+	}
+	if strings.Contains(zebraCmdFlag, "errors") {
+		status, err := zebra.Errors(ctx)
+		if err == nil {
+			fmt.Printf("errors: %s\nwarnings: %s\n", status.Errors, status.Warnings)
+			cmdSent = true
+		}
+	}
 	if strings.Contains(zebraCmdFlag, "config") {
-		info, err := getConfigFromZebra(networkIpFlag, networkPortFlag)
+		info, err := zebra.Config(ctx)
 		if err == nil {
 			fmt.Println(info)
 			cmdSent = true
 		}
 	}
 	if strings.Contains(zebraCmdFlag, "diag") {
-		info, err := getDiagFromZebra(networkIpFlag, networkPortFlag)
+		info, err := zebra.Diagnostics(ctx)
 		if err == nil {
 			fmt.Println(info)
 			cmdSent = true
@@ -63,7 +102,24 @@ func specialCmds(zebraCmdFlag, networkIpFlag, networkPortFlag string) bool {
 	return cmdSent
 }
 
+// subcommands are run with the remaining arguments instead of converting an image
+var subcommands = map[string]func(args []string) error{
+	"discover": discoverCmd,
+	"emulate":  emulateCmd,
+	"sgd":      sgdCmd,
+	"spool":    spoolCmd,
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		if cmd, ok := subcommands[os.Args[1]]; ok {
+			if err := cmd(os.Args[2:]); err != nil {
+				log.Fatal(err)
+			}
+			return
+		}
+	}
+
 	var filenameFlag string
 	var zebraCmdFlag string
 	var graphicTypeFlag string
@@ -71,21 +127,102 @@ func main() {
 	var networkIpFlag string
 	var networkPortFlag string
 	var imageResizeFlag float64
+	var imageResampleFlag string
+	var networkTimeoutFlag time.Duration
+	var deviceFlag string
+	var serialFlag string
+	var serialBaudFlag int
+	var serialParityFlag string
+	var serialFlowFlag string
+	var ippFlag string
+	var lpdFlag string
+	var lpdQueueFlag string
+	var tlsFlags tlsFlags
+	var balanceFlag string
+	var retriesFlag int
+	var waitFlag time.Duration
 	var graphicType zplgfa.GraphicType
 
 	flag.StringVar(&filenameFlag, "file", "", "filename to convert to zpl")
-	flag.StringVar(&zebraCmdFlag, "cmd", "", "send special command to printer [cancel,calib,feed,info,config,diag]")
+	flag.StringVar(&zebraCmdFlag, "cmd", "", "send special command to printer [cancel,calib,feed,info,status,ident,errors,config,diag]")
 	flag.StringVar(&graphicTypeFlag, "type", "CompressedASCII", "type of graphic field encoding")
 	flag.StringVar(&imageEditFlag, "edit", "", "manipulate the image [invert,monochrome]")
-	flag.StringVar(&networkIpFlag, "ip", "", "send zpl to printer")
+	flag.StringVar(&networkIpFlag, "ip", "", "send zpl to printer (IPv4, IPv6 or hostname), comma separated for a pool of printers")
+	flag.StringVar(&balanceFlag, "balance", "roundrobin", "how to choose a printer of the pool [roundrobin,leastqueued]")
 	flag.StringVar(&networkPortFlag, "port", "9100", "network port of printer")
+	flag.DurationVar(&networkTimeoutFlag, "timeout", printer.DefaultReadTimeout, "timeout for printer responses")
+	flag.StringVar(&deviceFlag, "device", "", "send zpl to the printer device file, e.g. /dev/usb/lp0")
+	flag.StringVar(&serialFlag, "serial", "", "send zpl to the printer at the serial port, e.g. /dev/ttyUSB0")
+	flag.IntVar(&serialBaudFlag, "baud", printer.DefaultBaudRate, "baud rate of the serial port")
+	flag.StringVar(&serialParityFlag, "parity", "none", "parity of the serial port [none,even,odd]")
+	flag.StringVar(&serialFlowFlag, "flow", "none", "flow control of the serial port [none,hardware,software]")
+	flag.StringVar(&ippFlag, "ipp", "", "print via IPP, e.g. ipp://cups.local:631/printers/zebra")
+	flag.StringVar(&lpdFlag, "lpd", "", "print via the LPD print server host[:port]")
+	flag.StringVar(&lpdQueueFlag, "queue", "zebra", "queue of the LPD print server")
+	tlsFlags.register(flag.CommandLine)
+	flag.IntVar(&retriesFlag, "retries", 0, "retry sending the label this many times until the printer confirms it")
+	flag.DurationVar(&waitFlag, "wait", 0, "wait this long for the label to be printed, implies confirmation")
 	flag.Float64Var(&imageResizeFlag, "resize", 1.0, "zoom/resize the image")
+	flag.StringVar(&imageResampleFlag, "resample", "mitchell", "resampling used to resize the image [mitchell,nearest,barcode]")
 
 	// load flag input arguments
 	flag.Parse()
 
-	// send special commands to printer
-	cmdSent := specialCmds(zebraCmdFlag, networkIpFlag, networkPortFlag)
+	var zebra *printer.Printer
+	var pool *printer.Pool
+	switch {
+	case strings.Contains(networkIpFlag, ","):
+		tlsConfig, port, err := tlsFlags.config(flag.CommandLine, networkPortFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		balancing, err := printer.ParseBalancing(balanceFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		var printers []*printer.Printer
+		for _, ip := range strings.Split(networkIpFlag, ",") {
+			p := printer.New(printerAddr(ip, port))
+			p.TLSConfig = tlsConfig
+			p.ReadTimeout = networkTimeoutFlag
+			printers = append(printers, p)
+		}
+		pool = printer.NewPool(balancing, printers...)
+	case networkIpFlag != "":
+		tlsConfig, port, err := tlsFlags.config(flag.CommandLine, networkPortFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		zebra = printer.New(printerAddr(networkIpFlag, port))
+		zebra.TLSConfig = tlsConfig
+	case deviceFlag != "":
+		zebra = printer.NewDevice(deviceFlag)
+	case serialFlag != "":
+		parity, err := printer.ParseParity(serialParityFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		flow, err := printer.ParseFlowControl(serialFlowFlag)
+		if err != nil {
+			log.Fatal(err)
+		}
+		zebra = printer.NewSerial(printer.Serial{Path: serialFlag, BaudRate: serialBaudFlag, Parity: parity, FlowControl: flow})
+	case ippFlag != "":
+		zebra = printer.NewIPP(ippFlag)
+	case lpdFlag != "":
+		zebra = printer.NewLPD(lpdFlag, lpdQueueFlag)
+	}
+	if zebra != nil {
+		zebra.ReadTimeout = networkTimeoutFlag
+	}
+
+	// send special commands to printer, or to every printer of the pool
+	cmdSent := specialCmds(zebraCmdFlag, zebra)
+	if pool != nil {
+		for _, m := range pool.Members() {
+			cmdSent = specialCmds(zebraCmdFlag, m.Printer) || cmdSent
+		}
+	}
 
 	// check input parameter
 	if filenameFlag == "" {
@@ -153,7 +290,7 @@ func main() {
 
 	// resize image
 	if imageResizeFlag != 1.0 {
-		img = resize.Resize(uint(float64(config.Width)*imageResizeFlag), uint(float64(config.Height)*imageResizeFlag), img, resize.MitchellNetravali)
+		img = resizeImage(img, imageResizeFlag, imageResampleFlag)
 	}
 
 	// flatten image
@@ -162,9 +299,49 @@ func main() {
 	// convert image to zpl compatible type
 	gfimg := zplgfa.ConvertToZPL(flat, graphicType)
 
-	if networkIpFlag != "" {
+	if pool != nil {
+		// send zpl to a healthy printer of the pool
+		pool.Check(context.Background())
+		var p *printer.Printer
+		var err error
+		if retriesFlag > 0 || waitFlag > 0 {
+			var delivery *printer.Delivery
+			opts := printer.DeliveryOptions{Attempts: retriesFlag + 1, PrintTimeout: waitFlag}
+			p, delivery, err = pool.Deliver(context.Background(), gfimg, opts)
+			if errors.Is(err, printer.ErrUnconfirmed) {
+				log.Printf("Warning: the label may not have been printed by %s, %s\n", p.Addr, err)
+				err = nil
+			} else if err == nil {
+				log.Printf("label %s after %d attempts\n", delivery.State, delivery.Attempts)
+			}
+		} else {
+			p, err = pool.Send(context.Background(), gfimg)
+		}
+		if err != nil && p != nil {
+			log.Fatalf("Error: sending the label to %s failed, it may have been printed, %s\n", p.Addr, err)
+		}
+		if err != nil {
+			for _, m := range pool.Members() {
+				log.Printf("%s: %v\n", m.Printer.Addr, m.Err)
+			}
+			log.Fatalf("Error: no printer of the pool took the label, %s\n", err)
+		}
+		log.Printf("label sent to %s\n", p.Addr)
+	} else if zebra != nil && (retriesFlag > 0 || waitFlag > 0) {
+		// deliver zpl to printer and report the state of the label
+		opts := printer.DeliveryOptions{Attempts: retriesFlag + 1, PrintTimeout: waitFlag}
+		delivery, err := zebra.Deliver(context.Background(), gfimg, opts)
+		if errors.Is(err, printer.ErrUnconfirmed) {
+			log.Printf("Warning: the label may not have been printed, %s\n", err)
+		} else if err != nil {
+			log.Fatalf("Error: the label failed after %d attempts, %s\n", delivery.Attempts, err)
+		}
+		log.Printf("label %s after %d attempts\n", delivery.State, delivery.Attempts)
+	} else if zebra != nil {
 		// send zpl to printer
-		sendDataToZebra(networkIpFlag, networkPortFlag, gfimg)
+		if err := zebra.Send(context.Background(), gfimg); err != nil {
+			log.Printf("Warning: could not send the label to the printer, %s\n", err)
+		}
 	} else {
 		// output zpl with graphic field data to stdout
 		fmt.Println(gfimg)
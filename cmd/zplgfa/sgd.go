@@ -0,0 +1,96 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/PaackEng/zplgfa/printer"
+)
+
+const sgdUsage = `usage: zplgfa sgd -ip host[,host...] [-port 9100] [-tls] command
+
+commands:
+  get name [name...]     print the values of the settings
+  set name value         change a setting
+  do name [value]        execute an action, e.g. device.reset
+  allconfig              print all settings as JSON
+`
+
+// sgdCmd runs the sgd subcommand, which reads and changes printer settings
+// via SGD (Set/Get/Do) on every printer given by -ip
+func sgdCmd(args []string) error {
+	var networkIpFlag string
+	var networkPortFlag string
+	var networkTimeoutFlag time.Duration
+	var tlsFlags tlsFlags
+
+	flags := flag.NewFlagSet("sgd", flag.ExitOnError)
+	flags.Usage = func() { fmt.Fprint(flags.Output(), sgdUsage) }
+	flags.StringVar(&networkIpFlag, "ip", "", "comma separated printers to configure")
+	flags.StringVar(&networkPortFlag, "port", "9100", "network port of the printers")
+	flags.DurationVar(&networkTimeoutFlag, "timeout", printer.DefaultReadTimeout, "timeout for printer responses")
+	tlsFlags.register(flags)
+	flags.Parse(args)
+
+	args = flags.Args()
+	if networkIpFlag == "" || len(args) == 0 {
+		flags.Usage()
+		return errors.New("sgd: printer and command required")
+	}
+
+	tlsConfig, port, err := tlsFlags.config(flags, networkPortFlag)
+	if err != nil {
+		return err
+	}
+
+	var failed bool
+	for _, ip := range strings.Split(networkIpFlag, ",") {
+		zebra := printer.New(printerAddr(ip, port))
+		zebra.ReadTimeout = networkTimeoutFlag
+		zebra.TLSConfig = tlsConfig
+		if err := sgd(context.Background(), zebra, args); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", ip, err)
+			failed = true
+		}
+	}
+	if failed {
+		return errors.New("sgd: failed on some printers")
+	}
+	return nil
+}
+
+func sgd(ctx context.Context, zebra *printer.Printer, args []string) error {
+	switch {
+	case args[0] == "get" && len(args) > 1:
+		values, err := zebra.GetVars(ctx, args[1:]...)
+		if err != nil {
+			return err
+		}
+		for _, name := range args[1:] {
+			fmt.Printf("%s %s = %q\n", zebra.Addr, name, values[name])
+		}
+	case args[0] == "set" && len(args) == 3:
+		return zebra.SetVar(ctx, args[1], args[2])
+	case args[0] == "do" && (len(args) == 2 || len(args) == 3):
+		return zebra.Do(ctx, args[1], strings.Join(args[2:], ""))
+	case args[0] == "allconfig" && len(args) == 1:
+		config, err := zebra.AllConfig(ctx)
+		if err != nil {
+			return err
+		}
+		out, err := json.MarshalIndent(config, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(out))
+	default:
+		return fmt.Errorf("invalid command %q, see zplgfa sgd -h", strings.Join(args, " "))
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/PaackEng/zplgfa"
+	"github.com/PaackEng/zplgfa/printer"
+	"github.com/PaackEng/zplgfa/spool"
+)
+
+const spoolUsage = `usage: zplgfa spool [-dir spool] [-listen :8080] -printer name=address [-printer ...]
+
+addresses are host[:port] of the raw port, ipp:// URLs or device files
+
+HTTP API:
+  POST   /jobs?printer=name[&type=CompressedASCII]   enqueue ZPL or an image (Content-Type image/...)
+  GET    /jobs[?printer=name]                        list jobs
+  GET    /jobs/id                                    show a job
+  POST   /jobs/id/retry                              resend an interrupted or failed job
+  DELETE /jobs/id                                    cancel a job
+
+flags:
+`
+
+// maxJobSize limits the size of a job received by the spool
+const maxJobSize = 64 << 20
+
+var (
+	errInvalidImage = errors.New("could not decode the image")
+	errJobTooLarge  = errors.New("job too large")
+)
+
+// printerFlags collects the -printer flags
+type printerFlags map[string]*printer.Printer
+
+func (pf printerFlags) String() string {
+	return ""
+}
+
+func (pf printerFlags) Set(value string) error {
+	name, addr := splitPrinterFlag(value)
+	if name == "" || addr == "" {
+		return errors.New("expected name=address")
+	}
+	switch {
+	case strings.HasPrefix(addr, "ipp://"), strings.HasPrefix(addr, "ipps://"):
+		pf[name] = printer.NewIPP(addr)
+	case strings.HasPrefix(addr, "/"):
+		pf[name] = printer.NewDevice(addr)
+	default:
+		pf[name] = printer.New(printerAddr(addr, "9100"))
+	}
+	return nil
+}
+
+func splitPrinterFlag(value string) (string, string) {
+	i := strings.Index(value, "=")
+	if i < 0 {
+		return "", ""
+	}
+	return value[:i], value[i+1:]
+}
+
+// spoolCmd runs the spool subcommand, a daemon which persists the jobs it
+// receives via HTTP and delivers them in order to the printers
+func spoolCmd(args []string) error {
+	var dirFlag string
+	var listenFlag string
+	var resendFlag bool
+	var retentionFlag time.Duration
+	printers := printerFlags{}
+
+	flags := flag.NewFlagSet("spool", flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), spoolUsage)
+		flags.PrintDefaults()
+	}
+	flags.StringVar(&dirFlag, "dir", "spool", "directory to persist the jobs in")
+	flags.StringVar(&listenFlag, "listen", ":8080", "address of the HTTP API")
+	flags.Var(printers, "printer", "printer as name=address, repeatable")
+	flags.BoolVar(&resendFlag, "resend-interrupted", false, "resend jobs interrupted by a crash, which may print them twice")
+	flags.DurationVar(&retentionFlag, "retention", spool.DefaultRetention, "time done and canceled jobs are kept, negative keeps them forever")
+	flags.Parse(args)
+
+	if len(printers) == 0 {
+		flags.Usage()
+		return errors.New("spool: at least one printer required")
+	}
+	s, err := spool.Open(dirFlag, printers, spool.Options{ResendInterrupted: resendFlag, Retention: retentionFlag})
+	if err != nil {
+		return err
+	}
+	for _, job := range s.Jobs("") {
+		if job.State == spool.Interrupted || job.State == spool.Failed {
+			log.Printf("Warning: job %d for %s %s, retry or cancel it\n", job.ID, job.Printer, job.State)
+		}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	server := &http.Server{Addr: listenFlag, Handler: spoolHandler(s)}
+	go func() {
+		<-ctx.Done()
+		shutdown, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		server.Shutdown(shutdown)
+	}()
+	done := make(chan struct{})
+	go func() {
+		s.Run(ctx)
+		close(done)
+	}()
+
+	log.Printf("spooling to %s, accepting jobs on %s\n", dirFlag, listenFlag)
+	err = server.ListenAndServe()
+	stop()
+	// wait for the jobs being sent
+	<-done
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// spoolHandler serves the HTTP API of the spool
+func spoolHandler(s *spool.Spool) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.Trim(r.URL.Path, "/")
+		switch {
+		case path == "jobs" && r.Method == http.MethodPost:
+			job, err := enqueue(s, w, r)
+			respondJSON(w, job, err)
+		case path == "jobs" && r.Method == http.MethodGet:
+			jobs := s.Jobs(r.URL.Query().Get("printer"))
+			if jobs == nil {
+				jobs = []spool.Job{}
+			}
+			respondJSON(w, jobs, nil)
+		case strings.HasPrefix(path, "jobs/"):
+			parts := strings.Split(strings.TrimPrefix(path, "jobs/"), "/")
+			id, err := strconv.ParseUint(parts[0], 10, 64)
+			if err != nil || len(parts) > 2 {
+				http.NotFound(w, r)
+				return
+			}
+			switch {
+			case len(parts) == 1 && r.Method == http.MethodGet:
+			case len(parts) == 1 && r.Method == http.MethodDelete:
+				err = s.Cancel(id)
+			case len(parts) == 2 && parts[1] == "retry" && r.Method == http.MethodPost:
+				err = s.Retry(id)
+			default:
+				http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+				return
+			}
+			if err != nil {
+				respondJSON(w, nil, err)
+				return
+			}
+			job, err := s.Job(id)
+			respondJSON(w, job, err)
+		default:
+			http.NotFound(w, r)
+		}
+	})
+}
+
+// enqueue adds the body of the request as job, images are converted
+func enqueue(s *spool.Spool, w http.ResponseWriter, r *http.Request) (spool.Job, error) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxJobSize))
+	if err != nil {
+		// the reader fails once the body exceeds the limit
+		if len(body) == maxJobSize {
+			return spool.Job{}, fmt.Errorf("%w, the limit is %d bytes", errJobTooLarge, maxJobSize)
+		}
+		return spool.Job{}, err
+	}
+	name := r.URL.Query().Get("printer")
+	if !strings.HasPrefix(r.Header.Get("Content-Type"), "image/") {
+		return s.Enqueue(name, string(body))
+	}
+	img, _, err := image.Decode(bytes.NewReader(body))
+	if err != nil {
+		return spool.Job{}, fmt.Errorf("%w, %s", errInvalidImage, err)
+	}
+	graphicType := zplgfa.CompressedASCII
+	switch strings.ToUpper(r.URL.Query().Get("type")) {
+	case "ASCII":
+		graphicType = zplgfa.ASCII
+	case "BINARY":
+		graphicType = zplgfa.Binary
+	}
+	return s.EnqueueImage(name, img, graphicType)
+}
+
+// respondJSON writes v as JSON, or err with a matching status code
+func respondJSON(w http.ResponseWriter, v interface{}, err error) {
+	if err != nil {
+		status := http.StatusInternalServerError
+		switch {
+		case errors.Is(err, spool.ErrUnknownPrinter), errors.Is(err, errInvalidImage):
+			status = http.StatusBadRequest
+		case errors.Is(err, spool.ErrNotFound):
+			status = http.StatusNotFound
+		case errors.Is(err, spool.ErrJobState):
+			status = http.StatusConflict
+		case errors.Is(err, errJobTooLarge):
+			status = http.StatusRequestEntityTooLarge
+		}
+		http.Error(w, err.Error(), status)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
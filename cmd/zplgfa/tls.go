@@ -0,0 +1,38 @@
+package main
+
+import (
+	"crypto/tls"
+	"flag"
+
+	"github.com/PaackEng/zplgfa/printer"
+)
+
+// tlsFlags are the flags enabling TLS for the raw TCP port
+type tlsFlags struct {
+	enabled  bool
+	caFile   string
+	insecure bool
+}
+
+func (tf *tlsFlags) register(flags *flag.FlagSet) {
+	flags.BoolVar(&tf.enabled, "tls", false, "connect to the raw TLS port of the printer (9143 unless -port is set)")
+	flags.StringVar(&tf.caFile, "tls-ca", "", "PEM file with the certificate authority of the printer, implies -tls")
+	flags.BoolVar(&tf.insecure, "tls-insecure", false, "skip the verification of the printer certificate, implies -tls")
+}
+
+// config returns the TLS configuration and the port of the printer, port is
+// replaced by the TLS port unless it was set explicitly
+func (tf *tlsFlags) config(flags *flag.FlagSet, port string) (*tls.Config, string, error) {
+	if !tf.enabled && tf.caFile == "" && !tf.insecure {
+		return nil, port, nil
+	}
+	portSet := false
+	flags.Visit(func(f *flag.Flag) {
+		portSet = portSet || f.Name == "port"
+	})
+	if !portSet {
+		port = printer.TLSPort
+	}
+	config, err := printer.NewTLSConfig(tf.caFile, tf.insecure)
+	return config, port, err
+}
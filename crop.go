@@ -0,0 +1,91 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+)
+
+type subImager interface {
+	SubImage(r image.Rectangle) image.Image
+}
+
+// boundedImage restricts the visible area of an image which doesn't
+// implement the SubImage method itself
+type boundedImage struct {
+	image.Image
+	rect image.Rectangle
+}
+
+func (b boundedImage) Bounds() image.Rectangle {
+	return b.rect
+}
+
+// subImage returns the part of img visible through r
+func subImage(img image.Image, r image.Rectangle) image.Image {
+	r = r.Intersect(img.Bounds())
+	if si, ok := img.(subImager); ok {
+		return si.SubImage(r)
+	}
+	return boundedImage{img, r}
+}
+
+// rowBlank reports whether row y of img contains no printed dots
+func rowBlank(img image.Image, y int) bool {
+	b := img.Bounds()
+	for x := b.Min.X; x < b.Max.X; x++ {
+		if isBlack(img.At(x, y).RGBA()) {
+			return false
+		}
+	}
+	return true
+}
+
+// columnBlank reports whether column x of img contains no printed dots
+// between the rows minY and maxY
+func columnBlank(img image.Image, x, minY, maxY int) bool {
+	for y := minY; y < maxY; y++ {
+		if isBlack(img.At(x, y).RGBA()) {
+			return false
+		}
+	}
+	return true
+}
+
+// CropBounds returns the smallest rectangle within img which contains every
+// pixel that would be printed as a black dot. Blank (white or transparent)
+// images result in an empty rectangle.
+func CropBounds(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	minY, maxY := b.Min.Y, b.Max.Y
+	for minY < maxY && rowBlank(img, minY) {
+		minY++
+	}
+	if minY == maxY {
+		return image.Rectangle{}
+	}
+	for rowBlank(img, maxY-1) {
+		maxY--
+	}
+	minX, maxX := b.Min.X, b.Max.X
+	for columnBlank(img, minX, minY, maxY) {
+		minX++
+	}
+	for columnBlank(img, maxX-1, minY, maxY) {
+		maxX--
+	}
+	return image.Rect(minX, minY, maxX, maxY)
+}
+
+// ConvertToZPLCropped works like ConvertToZPL, but trims blank border rows and
+// columns before encoding the Graphic Field. The Field Origin is moved by the
+// trimmed offset, so the graphic is printed at the same position as the
+// uncropped image. The returned rectangle holds the trimmed bounds within img.
+func ConvertToZPLCropped(img image.Image, graphicType GraphicType) (string, image.Rectangle) {
+	crop := CropBounds(img)
+	if crop.Empty() {
+		return "^XA,^FS\n^XZ\n", crop
+	}
+	offset := crop.Min.Sub(img.Bounds().Min)
+	gf := ConvertToGraphicField(subImage(img, crop), graphicType)
+	return fmt.Sprintf("^XA,^FS\n^FO%d,%d\n%s^FS,^XZ\n", offset.X, offset.Y, gf), crop
+}
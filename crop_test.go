@@ -0,0 +1,77 @@
+package zplgfa
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"strings"
+	"testing"
+)
+
+func whiteImage(w, h int) *image.NRGBA {
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+	return img
+}
+
+func fillRect(img draw.Image, r image.Rectangle) {
+	draw.Draw(img, r, image.Black, image.Point{}, draw.Src)
+}
+
+func Test_CropBounds(t *testing.T) {
+	img := whiteImage(64, 32)
+	fillRect(img, image.Rect(10, 5, 26, 12))
+	img.Set(40, 20, color.Black)
+
+	if got, want := CropBounds(img), image.Rect(10, 5, 41, 21); got != want {
+		t.Fatalf("CropBounds = %v, want %v", got, want)
+	}
+	if got := CropBounds(whiteImage(16, 16)); !got.Empty() {
+		t.Fatalf("CropBounds of blank image = %v, want empty", got)
+	}
+	transparent := image.NewNRGBA(image.Rect(0, 0, 16, 16))
+	if got := CropBounds(transparent); !got.Empty() {
+		t.Fatalf("CropBounds of transparent image = %v, want empty", got)
+	}
+}
+
+func Test_ConvertToZPLCropped(t *testing.T) {
+	img := whiteImage(64, 32)
+	fillRect(img, image.Rect(16, 8, 32, 16))
+
+	zpl, bounds := ConvertToZPLCropped(img, CompressedASCII)
+	if want := image.Rect(16, 8, 32, 16); bounds != want {
+		t.Fatalf("bounds = %v, want %v", bounds, want)
+	}
+	if !strings.Contains(zpl, "^FO16,8\n") {
+		t.Fatalf("expected field origin to be moved, got:\n%s", zpl)
+	}
+
+	block := whiteImage(16, 8)
+	fillRect(block, block.Bounds())
+	if gf := ConvertToGraphicField(block, CompressedASCII); !strings.Contains(zpl, gf) {
+		t.Fatalf("expected cropped graphic field\n%s\ngot:\n%s", gf, zpl)
+	}
+
+	zpl, bounds = ConvertToZPLCropped(whiteImage(16, 16), ASCII)
+	if !bounds.Empty() || strings.Contains(zpl, "^GF") {
+		t.Fatalf("expected empty label for blank image, got %v:\n%s", bounds, zpl)
+	}
+}
+
+func Test_ConvertToZPLCroppedOddWidth(t *testing.T) {
+	// 20 dots need two full bytes and a partial one per row
+	img := whiteImage(64, 32)
+	fillRect(img, image.Rect(3, 4, 23, 6))
+
+	zpl, bounds := ConvertToZPLCropped(img, ASCII)
+	if want := image.Rect(3, 4, 23, 6); bounds != want {
+		t.Fatalf("bounds = %v, want %v", bounds, want)
+	}
+	if !strings.Contains(zpl, "^GFA,14,6,3,\nFFFFF0\nFFFFF0\n") {
+		t.Fatalf("expected all 20 dots of every row, got:\n%s", zpl)
+	}
+	if got := ConvertToGraphicField(subImage(img, bounds), ASCII); !strings.Contains(zpl, got) {
+		t.Fatalf("expected the graphic field of the crop\n%s\ngot:\n%s", got, zpl)
+	}
+}
@@ -0,0 +1,93 @@
+package zplgfa
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// EscapeMode selects how EscapeFieldDataMode protects the command prefixes
+// ^ and ~ within field data
+type EscapeMode int
+
+const (
+	// EscapeAuto leaves text without prefixes as it is, uses prefix changes
+	// for text with many prefixes and hex escaping for all the rest
+	EscapeAuto EscapeMode = iota
+	// EscapeHex escapes the prefixes via ^FH (Field Hexadecimal Indicator)
+	EscapeHex
+	// EscapePrefix temporarily switches the prefixes via ^CC and ^CT to
+	// characters not used in the text
+	EscapePrefix
+)
+
+// autoPrefixThreshold is the number of prefixes from which on EscapeAuto
+// switches the prefixes instead of hex escaping each one of them
+const autoPrefixThreshold = 4
+
+// prefixCandidates are the characters used as replacement prefixes
+const prefixCandidates = "`|#@$%&*+=<>{}"
+
+// EscapeFieldData returns s as field data command (^FD), safe against
+// characters the printer would read as commands. Non-ASCII text is
+// prefixed by ^CI28, so the printer interprets the field as UTF-8.
+// Append ^FS to close the field.
+func EscapeFieldData(s string) string {
+	return EscapeFieldDataMode(s, EscapeAuto)
+}
+
+// EscapeFieldDataMode works like EscapeFieldData with the given EscapeMode
+func EscapeFieldDataMode(s string, mode EscapeMode) string {
+	encoding := ""
+	for _, r := range s {
+		if r >= utf8.RuneSelf {
+			encoding = "^CI28"
+			break
+		}
+	}
+
+	prefixes := strings.Count(s, "^") + strings.Count(s, "~")
+	if mode == EscapeAuto {
+		switch {
+		case prefixes == 0:
+			return encoding + "^FD" + s
+		case prefixes > autoPrefixThreshold:
+			mode = EscapePrefix
+		default:
+			mode = EscapeHex
+		}
+	}
+	if mode == EscapePrefix {
+		if zpl, ok := prefixEscaped(s); ok {
+			return encoding + zpl
+		}
+	}
+	r := strings.NewReplacer("_", "_5F", "^", "_5E", "~", "_7E")
+	return encoding + "^FH^FD" + r.Replace(s)
+}
+
+// prefixEscaped switches the caret and tilde prefixes used in s to unused
+// characters for the field data and back to the defaults right after it
+func prefixEscaped(s string) (string, bool) {
+	var free []byte
+	for i := 0; i < len(prefixCandidates); i++ {
+		if !strings.ContainsRune(s, rune(prefixCandidates[i])) {
+			free = append(free, prefixCandidates[i])
+		}
+	}
+	if len(free) < 2 {
+		return "", false
+	}
+	caret, tilde := free[0], free[1]
+
+	var before, after string
+	if strings.Contains(s, "~") {
+		before += fmt.Sprintf("^CT%c", tilde)
+		after = "^CT~"
+	}
+	if !strings.Contains(s, "^") {
+		return fmt.Sprintf("%s^FD%s%s", before, s, after), true
+	}
+	before += fmt.Sprintf("^CC%c", caret)
+	return fmt.Sprintf("%s%cFD%s%cCC^%s", before, caret, s, caret, after), true
+}
@@ -0,0 +1,30 @@
+package zplgfa
+
+import "testing"
+
+func Test_EscapeFieldData(t *testing.T) {
+	tests := []struct {
+		text string
+		mode EscapeMode
+		want string
+	}{
+		{"Jane Doe", EscapeAuto, "^FDJane Doe"},
+		{"Jane ^Doe_", EscapeAuto, "^FH^FDJane _5EDoe_5F"},
+		{"^^~~^", EscapeAuto, "^CT|^CC``FD^^~~^`CC^^CT~"},
+		{"Jane ~Doe", EscapeHex, "^FH^FDJane _7EDoe"},
+		{"Jane ^Doe", EscapePrefix, "^CC``FDJane ^Doe`CC^"},
+		{"Jane ~Doe", EscapePrefix, "^CT|^FDJane ~Doe^CT~"},
+		{"Jürgen ^", EscapeAuto, "^CI28^FH^FDJürgen _5E"},
+		{"東京", EscapeAuto, "^CI28^FD東京"},
+		{"`|#@$%&*+=<>{}^", EscapePrefix, "^FH^FD`|#@$%&*+=<>{}_5E"},
+	}
+	for _, tt := range tests {
+		got := EscapeFieldDataMode(tt.text, tt.mode)
+		if got != tt.want {
+			t.Errorf("EscapeFieldDataMode(%q, %d) = %q, want %q", tt.text, tt.mode, got, tt.want)
+		}
+		if err := ValidateZPL("^XA^FO0,0^A0N,20,20" + got + "^FS^XZ"); err != nil {
+			t.Errorf("escaped %q results in invalid ZPL: %v", tt.text, err)
+		}
+	}
+}
@@ -9,7 +9,7 @@ import (
 	"log"
 	"strings"
 
-	"github.com/ramirezalbert3/zplgfa"
+	"github.com/PaackEng/zplgfa"
 )
 
 func ExampleCompressASCII() {
@@ -54,5 +54,5 @@ func ExampleConvertToZPL() {
 
 	fmt.Println(zplstr)
 
-	// Output: ^XA,^FS^FO0,0^GFA,52,51,3,FFFF00::FE3F00::FFFF00FFE300::FFFF00E22300::FFFF00::^FS,^XZ
+	// Output: ^XA,^FS^FO0,0^GFA,52,51,3,FFFF80::FE3F80::FFFF80FFE380::FFFF80E22380::FFFF80::^FS,^XZ
 }
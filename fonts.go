@@ -0,0 +1,86 @@
+package zplgfa
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"golang.org/x/image/font/opentype"
+)
+
+// FontExtension selects the type of font file ~DY stores
+type FontExtension int
+
+const (
+	// TrueTypeFont is a TrueType (.TTF) or OpenType (.OTF) font
+	TrueTypeFont FontExtension = iota
+	// TrueTypeExtension is a TrueType Extension (.TTE), e.g. a subset of
+	// glyphs for a big font
+	TrueTypeExtension
+)
+
+func (fe FontExtension) String() string {
+	if fe == TrueTypeExtension {
+		return "E"
+	}
+	return "T"
+}
+
+// checkFont makes sure data holds a TrueType or OpenType font
+func checkFont(data []byte) error {
+	if _, err := opentype.Parse(data); err != nil {
+		return fmt.Errorf("invalid font data: %w", err)
+	}
+	return nil
+}
+
+func hexData(data []byte) string {
+	return strings.ToUpper(hex.EncodeToString(data))
+}
+
+// DownloadUnboundedFont returns a ~DU (Download Unbounded TrueType Font)
+// command, which stores the font data under name (e.g. "R:ARIAL.FNT").
+// ~DU is meant for big fonts, like Asian ones, on older firmware.
+func DownloadUnboundedFont(name string, data []byte) (string, error) {
+	if err := checkFont(data); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("~DU%s,%d,%s\n", name, len(data), hexData(data)), nil
+}
+
+// DownloadTrueTypeFont returns a ~DT (Download Bounded TrueType Font) command,
+// which stores the font data under name (e.g. "R:ARIAL.DAT")
+func DownloadTrueTypeFont(name string, data []byte) (string, error) {
+	if err := checkFont(data); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("~DT%s,%d,%s\n", name, len(data), hexData(data)), nil
+}
+
+// DownloadFont returns a ~DY (Download Objects) command, which stores a
+// TrueType or OpenType font under name (drive and up to 8 characters without
+// extension, e.g. "E:ARIAL"). The font data is sent hex encoded or, with the
+// Binary graphic type, as raw bytes.
+func DownloadFont(name string, ext FontExtension, data []byte, graphicType GraphicType) (string, error) {
+	if err := checkFont(data); err != nil {
+		return "", err
+	}
+	payload := hexData(data)
+	if graphicType == Binary {
+		payload = string(data)
+	}
+	return fmt.Sprintf("~DY%s,%s,%s,%d,,%s\n", name, graphicType.String(), ext.String(), len(data), payload), nil
+}
+
+// FontAlias returns a ^CW (Font Identifier) command, which assigns the
+// letter or digit id to the stored font file (e.g. "E:ARIAL.TTF"). Later
+// labels can use the font via ^A and the id.
+func FontAlias(id rune, file string) string {
+	return fmt.Sprintf("^CW%c,%s\n", id, file)
+}
+
+// FontByName returns a ^A@ (Use Font Name to Call Font) command selecting the
+// stored font file (e.g. "E:ARIAL.TTF") with a character height and width in dots
+func FontByName(file string, height, width int) string {
+	return fmt.Sprintf("^A@N,%d,%d,%s", height, width, file)
+}
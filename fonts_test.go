@@ -0,0 +1,56 @@
+package zplgfa
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+func Test_DownloadFont(t *testing.T) {
+	size := len(goregular.TTF)
+	tests := []struct {
+		name   string
+		cmd    func() (string, error)
+		prefix string
+	}{
+		{"DU", func() (string, error) { return DownloadUnboundedFont("R:GO.FNT", goregular.TTF) }, fmt.Sprintf("~DUR:GO.FNT,%d,", size)},
+		{"DT", func() (string, error) { return DownloadTrueTypeFont("R:GO.DAT", goregular.TTF) }, fmt.Sprintf("~DTR:GO.DAT,%d,", size)},
+		{"DY", func() (string, error) { return DownloadFont("E:GO", TrueTypeFont, goregular.TTF, ASCII) }, fmt.Sprintf("~DYE:GO,A,T,%d,,", size)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd, err := tt.cmd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !strings.HasPrefix(cmd, tt.prefix) {
+				t.Fatalf("expected prefix %q, got %q", tt.prefix, cmd[:len(tt.prefix)])
+			}
+			data, err := hex.DecodeString(strings.TrimSpace(cmd[len(tt.prefix):]))
+			if err != nil || string(data) != string(goregular.TTF) {
+				t.Fatalf("font data not hex encoded: %v", err)
+			}
+		})
+	}
+
+	cmd, err := DownloadFont("E:GO", TrueTypeExtension, goregular.TTF, Binary)
+	if err != nil || !strings.HasPrefix(cmd, fmt.Sprintf("~DYE:GO,B,E,%d,,", size)) || !strings.Contains(cmd, string(goregular.TTF)) {
+		t.Fatalf("unexpected binary ~DY command (err %v)", err)
+	}
+
+	if _, err := DownloadFont("E:BAD", TrueTypeFont, []byte("not a font"), ASCII); err == nil {
+		t.Fatal("expected an error for invalid font data")
+	}
+}
+
+func Test_FontAlias(t *testing.T) {
+	if got := FontAlias('Q', "E:GO.TTF"); got != "^CWQ,E:GO.TTF\n" {
+		t.Fatalf("unexpected ^CW command %q", got)
+	}
+	if got := FontByName("E:GO.TTF", 30, 20); got != "^A@N,30,20,E:GO.TTF" {
+		t.Fatalf("unexpected ^A@ command %q", got)
+	}
+}
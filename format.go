@@ -0,0 +1,121 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"reflect"
+	"strings"
+)
+
+// Format is a label layout stored in the printer memory with ^DF (Download
+// Format). Its ^FN (Field Number) fields are filled per print job with the
+// small payload of Recall, so graphics are sent only once.
+type Format struct {
+	name     string
+	elements []string
+	fields   []string
+}
+
+// NewFormat creates an empty format, which will be stored under name
+// (e.g. "R:LABEL.ZPL")
+func NewFormat(name string) *Format {
+	return &Format{name: name}
+}
+
+// Name returns the name the format is stored under
+func (f *Format) Name() string {
+	return f.name
+}
+
+// Raw adds ZPL code to the format as it is
+func (f *Format) Raw(zpl string) *Format {
+	f.elements = append(f.elements, zpl)
+	return f
+}
+
+// Graphic adds img as Graphic Field at the position x, y
+func (f *Format) Graphic(x, y int, img image.Image, graphicType GraphicType) *Format {
+	return f.Raw(fmt.Sprintf("^FO%d,%d\n%s^FS\n", x, y, ConvertToGraphicField(img, graphicType)))
+}
+
+// Field adds a placeholder for the record value named variable at the
+// position x, y. The font is selected by the ZPL command in font, e.g.
+// "^A0N,30,30" or the result of FontByName. Using the same variable more than
+// once prints its value at every position.
+func (f *Format) Field(x, y int, font, variable string) *Format {
+	return f.Raw(fmt.Sprintf("^FO%d,%d%s^FN%d^FS\n", x, y, font, f.fieldNumber(variable)))
+}
+
+// fieldNumber returns the ^FN number of variable, new variables get the next free number
+func (f *Format) fieldNumber(variable string) int {
+	for i, name := range f.fields {
+		if name == variable {
+			return i + 1
+		}
+	}
+	f.fields = append(f.fields, variable)
+	return len(f.fields)
+}
+
+// String returns the ^DF (Download Format) label, which stores the format on the printer
+func (f *Format) String() string {
+	return fmt.Sprintf("^XA\n^DF%s^FS\n%s^XZ\n", f.name, strings.Join(f.elements, ""))
+}
+
+// recordValues collects the values of a map or struct by their variable
+// name. Struct fields are named by their `zpl` tag or else their Go name.
+func recordValues(record interface{}) (map[string]string, error) {
+	values := map[string]string{}
+	v := reflect.Indirect(reflect.ValueOf(record))
+	switch v.Kind() {
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String {
+			return nil, fmt.Errorf("record map needs string keys, got %s", v.Type())
+		}
+		iter := v.MapRange()
+		for iter.Next() {
+			values[iter.Key().String()] = fmt.Sprint(iter.Value().Interface())
+		}
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.PkgPath != "" {
+				continue
+			}
+			name := field.Name
+			if tag, ok := field.Tag.Lookup("zpl"); ok {
+				if tag == "-" {
+					continue
+				}
+				name = tag
+			}
+			values[name] = fmt.Sprint(v.Field(i).Interface())
+		}
+	default:
+		return nil, fmt.Errorf("record has to be a map or struct, got %T", record)
+	}
+	return values, nil
+}
+
+// Recall returns the ^XF (Recall Format) label, which prints the stored format
+// filled with the values of record. A record is a map with string keys or a
+// struct, whose fields are matched by their `zpl` tag or their name. The
+// values are escaped via EscapeFieldData.
+func (f *Format) Recall(record interface{}) (string, error) {
+	values, err := recordValues(record)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "^XA\n^XF%s^FS\n", f.name)
+	for i, name := range f.fields {
+		value, ok := values[name]
+		if !ok {
+			return "", fmt.Errorf("record has no value for field %q", name)
+		}
+		fmt.Fprintf(&sb, "^FN%d%s^FS\n", i+1, EscapeFieldData(value))
+	}
+	sb.WriteString("^XZ\n")
+	return sb.String(), nil
+}
@@ -0,0 +1,57 @@
+package zplgfa
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func testFormat() *Format {
+	logo := whiteImage(16, 8)
+	fillRect(logo, image.Rect(0, 0, 8, 8))
+	return NewFormat("R:SHIP.ZPL").
+		Graphic(10, 10, logo, ASCII).
+		Field(10, 50, "^A0N,30,30", "name").
+		Field(10, 90, "^A0N,20,20", "tracking").
+		Field(300, 90, "^A0N,20,20", "name")
+}
+
+func Test_FormatString(t *testing.T) {
+	got := testFormat().String()
+	want := "^XA\n^DFR:SHIP.ZPL^FS\n" +
+		"^FO10,10\n^GFA,40,16,2,\nFF00\nFF00\nFF00\nFF00\nFF00\nFF00\nFF00\nFF00\n^FS\n" +
+		"^FO10,50^A0N,30,30^FN1^FS\n" +
+		"^FO10,90^A0N,20,20^FN2^FS\n" +
+		"^FO300,90^A0N,20,20^FN1^FS\n" +
+		"^XZ\n"
+	if got != want {
+		t.Fatalf("unexpected stored format, got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func Test_FormatRecall(t *testing.T) {
+	format := testFormat()
+	want := "^XA\n^XFR:SHIP.ZPL^FS\n^FN1^FDJane Doe^FS\n^FN2^FD42^FS\n^XZ\n"
+
+	got, err := format.Recall(map[string]interface{}{"name": "Jane Doe", "tracking": 42, "unused": true})
+	if err != nil || got != want {
+		t.Fatalf("Recall(map) = %q, %v, want %q", got, err, want)
+	}
+
+	type shipment struct {
+		Name     string `zpl:"name"`
+		Tracking int    `zpl:"tracking"`
+		Internal string `zpl:"-"`
+	}
+	got, err = format.Recall(&shipment{Name: "Jane Doe", Tracking: 42})
+	if err != nil || got != want {
+		t.Fatalf("Recall(struct) = %q, %v, want %q", got, err, want)
+	}
+
+	if _, err := format.Recall(map[string]string{"name": "Jane Doe"}); err == nil || !strings.Contains(err.Error(), "tracking") {
+		t.Fatalf("expected an error for the missing field, got %v", err)
+	}
+	if _, err := format.Recall(42); err == nil {
+		t.Fatal("expected an error for an invalid record")
+	}
+}
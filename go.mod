@@ -5,4 +5,8 @@ go 1.17
 require (
 	github.com/anthonynsimon/bild v0.13.0
 	github.com/nfnt/resize v0.0.0-20180221191011-83c6a9932646
+	golang.org/x/image v0.10.0
+	golang.org/x/sys v0.10.0
 )
+
+require golang.org/x/text v0.11.0 // indirect
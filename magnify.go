@@ -0,0 +1,93 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+)
+
+// MaxMagnification is the biggest magnification factor ^XG (Recall Graphic) supports
+const MaxMagnification = 10
+
+// blocksUniform reports whether the bitmap consists of uniform squares of
+// scale × scale dots. Partial squares at the right and bottom edge have to be
+// white, since magnifying them again would print dots outside the image.
+func (bm *bitmap) blocksUniform(scale int) bool {
+	for by := 0; by < bm.height; by += scale {
+		for bx := 0; bx < bm.width; bx += scale {
+			partial := bx+scale > bm.width || by+scale > bm.height
+			black := bm.black(bx, by)
+			if partial && black {
+				return false
+			}
+			for y := by; y < by+scale && y < bm.height; y++ {
+				for x := bx; x < bx+scale && x < bm.width; x++ {
+					if bm.black(x, y) != black {
+						return false
+					}
+				}
+			}
+		}
+	}
+	return true
+}
+
+// downscale shrinks the bitmap by an integer factor using the top left dot of every square
+func (bm *bitmap) downscale(scale int) *bitmap {
+	small := newBitmap((bm.width+scale-1)/scale, (bm.height+scale-1)/scale)
+	for y := 0; y < small.height; y++ {
+		for x := 0; x < small.width; x++ {
+			small.set(x, y, bm.black(x*scale, y*scale))
+		}
+	}
+	return small
+}
+
+// DetectScale returns the biggest integer factor (up to MaxMagnification) by
+// which img is an upscaled version of a smaller picture, e.g. 2 or 3 for
+// pixel art graphics and barcodes rendered at 2x or 3x. Images which can't be
+// reduced without losing dots result in 1.
+func DetectScale(img image.Image) int {
+	bm := bitmapFromImage(img)
+	for scale := MaxMagnification; scale > 1; scale-- {
+		if bm.blocksUniform(scale) {
+			return scale
+		}
+	}
+	return 1
+}
+
+// Downscale shrinks img by the integer factor scale using nearest-neighbour
+// sampling on the thresholded dots, the counterpart of a ^XG magnification
+func Downscale(img image.Image, scale int) image.Image {
+	if scale < 1 {
+		scale = 1
+	}
+	return bitmapFromImage(img).downscale(scale)
+}
+
+// ConvertToStoredGraphic converts img to a ~DG (Download Graphic) command, which
+// stores the graphic in the printer memory under name (e.g. "R:LOGO.GRF") to
+// be recalled with ^XG. ~DG only accepts ASCII hex data, so the Binary type
+// is encoded as ASCII.
+func ConvertToStoredGraphic(img image.Image, graphicType GraphicType, name string) string {
+	if graphicType == Binary {
+		graphicType = ASCII
+	}
+	data, width, height := encodeGraphic(img, graphicType)
+	return fmt.Sprintf("~DG%s,%d,%d,\n", name, width*height, width) + data.String()
+}
+
+// ConvertToZPLMagnified stores a downscaled copy of img under name and recalls
+// it with a ^XG magnification of scale, so the printer does the upscaling and
+// a fraction of the data is sent. A scale of 0 or less detects the factor via
+// DetectScale, which never loses any dots.
+func ConvertToZPLMagnified(img image.Image, graphicType GraphicType, name string, scale int) string {
+	if scale <= 0 {
+		scale = DetectScale(img)
+	}
+	if scale > MaxMagnification {
+		scale = MaxMagnification
+	}
+	stored := ConvertToStoredGraphic(Downscale(img, scale), graphicType, name)
+	return fmt.Sprintf("%s^XA,^FS\n^FO0,0\n^XG%s,%d,%d^FS,^XZ\n", stored, name, scale, scale)
+}
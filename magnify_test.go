@@ -0,0 +1,76 @@
+package zplgfa
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+// pixelArt returns an 8x6 pattern upscaled by the given factor and the pattern itself
+func pixelArt(scale int) (*image.NRGBA, []string) {
+	pattern := []string{
+		"X..XX..X",
+		".X....X.",
+		"..XXXX..",
+		"X.X..X.X",
+		"........",
+		"XXX.X.XX",
+	}
+	img := whiteImage(8*scale, 6*scale)
+	for y, row := range pattern {
+		for x, c := range row {
+			if c == 'X' {
+				fillRect(img, image.Rect(x*scale, y*scale, (x+1)*scale, (y+1)*scale))
+			}
+		}
+	}
+	return img, pattern
+}
+
+func Test_DetectScale(t *testing.T) {
+	for _, scale := range []int{1, 2, 3, 7} {
+		img, _ := pixelArt(scale)
+		if got := DetectScale(img); got != scale {
+			t.Errorf("DetectScale of %dx pixel art = %d", scale, got)
+		}
+	}
+
+	// a black partial block at the edge would grow the image when magnified
+	img, _ := pixelArt(2)
+	fillRect(img, image.Rect(14, 0, 16, 1))
+	if got := DetectScale(subImage(img, image.Rect(0, 0, 15, 12))); got != 1 {
+		t.Errorf("DetectScale with black partial block = %d, want 1", got)
+	}
+}
+
+func Test_Downscale(t *testing.T) {
+	img, pattern := pixelArt(3)
+	small := Downscale(img, 3)
+	if size := small.Bounds().Size(); size != image.Pt(8, 6) {
+		t.Fatalf("unexpected size %v", size)
+	}
+	for y, row := range pattern {
+		for x, c := range row {
+			if black := isBlack(small.At(x, y).RGBA()); black != (c == 'X') {
+				t.Fatalf("dot %d,%d: got black=%v", x, y, black)
+			}
+		}
+	}
+}
+
+func Test_ConvertToZPLMagnified(t *testing.T) {
+	img, _ := pixelArt(3)
+	zpl := ConvertToZPLMagnified(img, ASCII, "R:ART.GRF", 0)
+	if !strings.HasPrefix(zpl, "~DGR:ART.GRF,6,1,\n") {
+		t.Fatalf("unexpected stored graphic:\n%s", zpl)
+	}
+	if !strings.Contains(zpl, "^XGR:ART.GRF,3,3^FS") {
+		t.Fatalf("expected recall with magnification 3:\n%s", zpl)
+	}
+	if full := ConvertToZPL(img, ASCII); len(zpl)*2 > len(full) {
+		t.Fatalf("expected a smaller payload, got %d bytes instead of %d", len(zpl), len(full))
+	}
+	if zpl := ConvertToZPLMagnified(img, Binary, "R:ART.GRF", 0); strings.Contains(zpl, "\x00") {
+		t.Fatalf("expected binary type to fall back to ASCII:\n%q", zpl)
+	}
+}
@@ -0,0 +1,70 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"strings"
+)
+
+// MMToDots converts a length in millimeters to printer dots at the given
+// resolution in dots per inch (e.g. 203, 300 or 600)
+func MMToDots(mm float64, dpi int) int {
+	return int(math.Round(mm * float64(dpi) / 25.4))
+}
+
+// PageOptions controls how tall images are split across multiple labels
+type PageOptions struct {
+	// MarginTop and MarginBottom are the dots kept blank at the top and the
+	// bottom of every label
+	MarginTop, MarginBottom int
+	// Overlap is the number of rows of a page repeated at the top of the next one
+	Overlap int
+	// SearchRows is the maximum number of rows a page break may be moved up
+	// to find a blank row, so text lines aren't cut in half
+	SearchRows int
+}
+
+// Pages splits img into the areas printed on labels of labelHeight dots
+func Pages(img image.Image, labelHeight int, opts PageOptions) []image.Rectangle {
+	bounds := img.Bounds()
+	content := labelHeight - opts.MarginTop - opts.MarginBottom
+	if content < 1 {
+		content = 1
+	}
+	var pages []image.Rectangle
+	for y := bounds.Min.Y; y < bounds.Max.Y; {
+		end := y + content
+		if end >= bounds.Max.Y {
+			pages = append(pages, image.Rect(bounds.Min.X, y, bounds.Max.X, bounds.Max.Y))
+			break
+		}
+		// move the break up to the nearest blank row, but keep the page
+		// longer than the overlap, otherwise there is no progress
+		for row := end; row > end-opts.SearchRows && row > y+opts.Overlap+1; row-- {
+			if rowBlank(img, row-1) {
+				end = row
+				break
+			}
+		}
+		pages = append(pages, image.Rect(bounds.Min.X, y, bounds.Max.X, end))
+		if next := end - opts.Overlap; next > y {
+			y = next
+		} else {
+			y = end
+		}
+	}
+	return pages
+}
+
+// ConvertToZPLPages converts a tall image (e.g. a receipt or a pick list) to a
+// ZPL stream of multiple labels with a height of labelHeight dots. Every page
+// is a format of its own, starting with ^XA and ending with ^XZ.
+func ConvertToZPLPages(img image.Image, graphicType GraphicType, labelHeight int, opts PageOptions) string {
+	var sb strings.Builder
+	for _, page := range Pages(img, labelHeight, opts) {
+		gf := ConvertToGraphicField(subImage(img, page), graphicType)
+		fmt.Fprintf(&sb, "^XA,^FS\n^LL%d\n^FO0,%d\n%s^FS,^XZ\n", labelHeight, opts.MarginTop, gf)
+	}
+	return sb.String()
+}
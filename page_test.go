@@ -0,0 +1,59 @@
+package zplgfa
+
+import (
+	"image"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// textLines returns an image with 10 dots high black lines every 15 rows
+func textLines(w, h int) *image.NRGBA {
+	img := whiteImage(w, h)
+	for y := 0; y < h; y += 15 {
+		fillRect(img, image.Rect(0, y, w, y+10))
+	}
+	return img
+}
+
+func Test_MMToDots(t *testing.T) {
+	if got := MMToDots(25.4, 203); got != 203 {
+		t.Fatalf("MMToDots(25.4, 203) = %d, want 203", got)
+	}
+	if got := MMToDots(152.4, 300); got != 1800 {
+		t.Fatalf("MMToDots(152.4, 300) = %d, want 1800", got)
+	}
+}
+
+func Test_Pages(t *testing.T) {
+	img := textLines(16, 100)
+	rect := func(y0, y1 int) image.Rectangle { return image.Rect(0, y0, 16, y1) }
+
+	tests := []struct {
+		name string
+		opts PageOptions
+		want []image.Rectangle
+	}{
+		{"plain", PageOptions{}, []image.Rectangle{rect(0, 32), rect(32, 64), rect(64, 96), rect(96, 100)}},
+		{"search", PageOptions{SearchRows: 10}, []image.Rectangle{rect(0, 30), rect(30, 60), rect(60, 90), rect(90, 100)}},
+		{"overlap", PageOptions{Overlap: 4}, []image.Rectangle{rect(0, 32), rect(28, 60), rect(56, 88), rect(84, 100)}},
+		{"margins", PageOptions{MarginTop: 6, MarginBottom: 6}, []image.Rectangle{rect(0, 20), rect(20, 40), rect(40, 60), rect(60, 80), rect(80, 100)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Pages(img, 32, tt.opts); !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Pages = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ConvertToZPLPages(t *testing.T) {
+	zpl := ConvertToZPLPages(textLines(16, 100), CompressedASCII, 40, PageOptions{MarginTop: 8, SearchRows: 10})
+	if n := strings.Count(zpl, "^XA"); n != 4 {
+		t.Fatalf("expected 4 labels, got %d:\n%s", n, zpl)
+	}
+	if strings.Count(zpl, "^XZ") != 4 || strings.Count(zpl, "^FO0,8\n") != 4 || strings.Count(zpl, "^LL40\n") != 4 {
+		t.Fatalf("unexpected label formats:\n%s", zpl)
+	}
+}
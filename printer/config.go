@@ -0,0 +1,69 @@
+package printer
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Configuration is the configuration of a printer as printed on its
+// configuration label (^HH). Values holds every setting by its name, the
+// common ones are converted to typed fields as well.
+type Configuration struct {
+	Values map[string]string
+
+	Darkness    float64
+	PrintSpeed  float64 // in inches per second
+	PrintWidth  int     // in dots
+	LabelLength int     // in dots
+	MediaType   string
+	PrintMode   string
+	PrintMethod string
+}
+
+// configGap separates the value from the name of a setting
+var configGap = regexp.MustCompile(`\s{2,}`)
+
+// leadingNumber matches the number at the start of a value like "4.0 IPS"
+var leadingNumber = regexp.MustCompile(`^[+-]?\d+(\.\d+)?`)
+
+func parseNumber(value string) float64 {
+	n, _ := strconv.ParseFloat(leadingNumber.FindString(value), 64)
+	return n
+}
+
+// ParseConfiguration parses the configuration label text a printer returns
+// for ^HH. Every line holds the value of a setting followed by its name, lines
+// without a value (like the headline) are skipped.
+func ParseConfiguration(text string) *Configuration {
+	config := &Configuration{Values: map[string]string{}}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.Trim(line, " \t\r\x02\x03")
+		gaps := configGap.FindAllStringIndex(line, -1)
+		if len(gaps) == 0 {
+			continue
+		}
+		last := gaps[len(gaps)-1]
+		value, name := strings.TrimSpace(line[:last[0]]), strings.TrimSpace(line[last[1]:])
+		config.Values[name] = value
+	}
+
+	config.Darkness = parseNumber(config.Values["DARKNESS"])
+	config.PrintSpeed = parseNumber(config.Values["PRINT SPEED"])
+	config.PrintWidth = int(parseNumber(config.Values["PRINT WIDTH"]))
+	config.LabelLength = int(parseNumber(config.Values["LABEL LENGTH"]))
+	config.MediaType = config.Values["MEDIA TYPE"]
+	config.PrintMode = config.Values["PRINT MODE"]
+	config.PrintMethod = config.Values["PRINT METHOD"]
+	return config
+}
+
+// Configuration queries the configuration of the printer via ^HH
+func (p *Printer) Configuration(ctx context.Context) (*Configuration, error) {
+	text, err := p.Config(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return ParseConfiguration(text), nil
+}
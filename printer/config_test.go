@@ -0,0 +1,55 @@
+package printer
+
+import (
+	"context"
+	"testing"
+)
+
+const configurationLabel = "\x02  PRINTER CONFIGURATION\r\n\r\n" +
+	"Zebra Technologies\r\n" +
+	"ZTC ZT410-203dpi ZPL\r\n" +
+	"  +10.0               DARKNESS\r\n" +
+	"  MEDIUM              DARKNESS SWITCH\r\n" +
+	"  4.0 IPS             PRINT SPEED\r\n" +
+	"  +000                TEAR OFF ADJUST\r\n" +
+	"  TEAR OFF            PRINT MODE\r\n" +
+	"  GAP/NOTCH           MEDIA TYPE\r\n" +
+	"  WEB                 SENSOR SELECT\r\n" +
+	"  THERMAL-TRANS.      PRINT METHOD\r\n" +
+	"  832 8/MM FULL       PRINT WIDTH\r\n" +
+	"  1215                LABEL LENGTH\r\n" +
+	"  39.0IN   988MM      MAXIMUM LENGTH\r\n" +
+	"FIRMWARE IN THIS PRINTER IS COPYRIGHTED\r\n\x03"
+
+func TestParseConfiguration(t *testing.T) {
+	config := ParseConfiguration(configurationLabel)
+	if config.Darkness != 10 || config.PrintSpeed != 4 || config.PrintWidth != 832 || config.LabelLength != 1215 {
+		t.Fatalf("unexpected numeric values %+v", config)
+	}
+	if config.MediaType != "GAP/NOTCH" || config.PrintMode != "TEAR OFF" || config.PrintMethod != "THERMAL-TRANS." {
+		t.Fatalf("unexpected settings %+v", config)
+	}
+	for name, value := range map[string]string{
+		"DARKNESS SWITCH": "MEDIUM",
+		"TEAR OFF ADJUST": "+000",
+		"MAXIMUM LENGTH":  "39.0IN   988MM",
+	} {
+		if got := config.Values[name]; got != value {
+			t.Errorf("%s: expected %q, got %q", name, value, got)
+		}
+	}
+	if len(config.Values) != 11 {
+		t.Fatalf("expected 11 settings, got %d: %v", len(config.Values), config.Values)
+	}
+}
+
+func TestConfiguration(t *testing.T) {
+	p := serve(t, respond(map[string]string{"^XA^HH^XZ": configurationLabel}))
+	config, err := p.Configuration(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if config.PrintWidth != 832 || config.MediaType != "GAP/NOTCH" {
+		t.Fatalf("unexpected configuration %+v", config)
+	}
+}
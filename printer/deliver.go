@@ -0,0 +1,268 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// JobState is the state of a job after its delivery
+type JobState int
+
+// Job states
+const (
+	// JobFailed means the job couldn't be delivered
+	JobFailed JobState = iota
+	// JobSent means the job was written, but its receipt couldn't be
+	// confirmed, e.g. as it contains no format or the transport has no
+	// responses
+	JobSent
+	// JobQueued means the formats of the job are in the buffer of the printer
+	JobQueued
+	// JobPrinted means the label counter of the printer went up by more
+	// than the formats buffered before the job, or its buffer was emptied
+	// after the job was queued
+	JobPrinted
+)
+
+func (s JobState) String() string {
+	names := []string{"failed", "sent", "queued", "printed"}
+	if s >= 0 && int(s) < len(names) {
+		return names[s]
+	}
+	return "unknown (" + strconv.Itoa(int(s)) + ")"
+}
+
+// MarshalText encodes the state by its name
+func (s JobState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes the name of a state
+func (s *JobState) UnmarshalText(text []byte) error {
+	for state := JobFailed; state <= JobPrinted; state++ {
+		if state.String() == string(text) {
+			*s = state
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown job state %q", text)
+}
+
+// Defaults of DeliveryOptions
+const (
+	DefaultAttempts     = 5
+	DefaultBackoff      = 500 * time.Millisecond
+	DefaultMaxBackoff   = 10 * time.Second
+	DefaultPollInterval = 200 * time.Millisecond
+)
+
+// DeliveryOptions control Deliver, zero values select the defaults
+type DeliveryOptions struct {
+	// Attempts limits the number of times the job is sent
+	Attempts int
+	// Backoff is the pause before the second attempt, it doubles with every
+	// further attempt up to MaxBackoff
+	Backoff    time.Duration
+	MaxBackoff time.Duration
+	// PrintTimeout limits the wait for the job to be printed after it was
+	// queued, zero doesn't wait
+	PrintTimeout time.Duration
+	// PollInterval is the pause between status queries
+	PollInterval time.Duration
+}
+
+func (o DeliveryOptions) withDefaults() DeliveryOptions {
+	if o.Attempts <= 0 {
+		o.Attempts = DefaultAttempts
+	}
+	if o.Backoff <= 0 {
+		o.Backoff = DefaultBackoff
+	}
+	if o.MaxBackoff <= 0 {
+		o.MaxBackoff = DefaultMaxBackoff
+	}
+	if o.PollInterval <= 0 {
+		o.PollInterval = DefaultPollInterval
+	}
+	return o
+}
+
+// Delivery is the result of Deliver
+type Delivery struct {
+	State JobState
+	// Attempts is the number of times the job was sent
+	Attempts int
+	// Status is the last status of the printer, nil if none was received
+	Status *HostStatus
+	// Err is the error of the last attempt, if the job failed or wasn't
+	// confirmed
+	Err error
+}
+
+// ErrUnconfirmed is wrapped by the error of Deliver if the job was written,
+// but the printer didn't confirm it. The job may have been printed.
+var ErrUnconfirmed = errors.New("the printer didn't confirm the receipt of the job")
+
+// odometerVar is the SGD setting counting the printed labels
+const odometerVar = "odometer.total_label_count"
+
+// Deliver sends the ZPL data to the printer like Send, but confirms the
+// receipt: within ReadTimeout, either the label counter of the printer
+// (SGD odometer.total_label_count) has to go up by more than the formats
+// buffered before the job, or the number of formats in its buffer (~HS) has
+// to go up. Attempts which fail before the job was written, like
+// refused connections, are repeated with exponential backoff. Once queued,
+// Deliver waits up to PrintTimeout for the buffer to be emptied.
+//
+// The confirmation assumes nobody else sends jobs to the printer meanwhile.
+// Printers without SGD answer no getvar, which takes ReadTimeout per
+// attempt. Transports without responses, like IPP and LPD, only repeat
+// failed submissions.
+//
+// The returned error is the error of the delivery if the job failed. A job
+// which was written but not confirmed isn't sent again, as it may have been
+// printed: it is JobSent and the error wraps ErrUnconfirmed.
+func (p *Printer) Deliver(ctx context.Context, zpl string, opts DeliveryOptions) (*Delivery, error) {
+	opts = opts.withDefaults()
+	d := &Delivery{}
+	backoff := opts.Backoff
+	for d.Attempts < opts.Attempts {
+		if d.Attempts > 0 {
+			if err := sleep(ctx, backoff); err != nil {
+				break
+			}
+			if backoff *= 2; backoff > opts.MaxBackoff {
+				backoff = opts.MaxBackoff
+			}
+		}
+		d.Attempts++
+		d.State, d.Err = p.deliver(ctx, zpl, d, opts.PollInterval)
+		if d.Err == nil || d.State != JobFailed || ctx.Err() != nil {
+			break
+		}
+	}
+	if d.Err != nil {
+		return d, d.Err
+	}
+	if d.State == JobQueued && opts.PrintTimeout > 0 {
+		p.waitPrinted(ctx, d, opts)
+	}
+	return d, nil
+}
+
+// deliver makes a single attempt to deliver the job on one connection. Once
+// the job is written, it isn't JobFailed anymore.
+func (p *Printer) deliver(ctx context.Context, zpl string, d *Delivery, poll time.Duration) (JobState, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return JobFailed, err
+	}
+	if _, ok := c.Conn.(*jobConn); ok || !strings.Contains(strings.ToUpper(zpl), "^XA") {
+		err = c.send(zpl)
+		if cerr := c.Close(); err == nil && cerr != nil {
+			err = c.opErr("write", cerr)
+		}
+		if err != nil {
+			return JobFailed, err
+		}
+		return JobSent, nil
+	}
+	defer c.Close()
+
+	// printers without SGD don't answer, they are confirmed via ~HS only
+	printed, err := c.odometer()
+	if err != nil && ctx.Err() != nil {
+		return JobFailed, c.opErr("read", err)
+	}
+	before, err := c.status()
+	if err != nil {
+		return JobFailed, err
+	}
+	d.Status = before
+	if err := c.send(zpl); err != nil {
+		return JobFailed, err
+	}
+
+	// the format may still be received when ~HS is answered
+	unconfirmed := func(err error) (JobState, error) {
+		return JobSent, p.err("read", fmt.Errorf("%w, %v", ErrUnconfirmed, err))
+	}
+	end := time.Now().Add(p.ReadTimeout)
+	for {
+		status, err := c.status()
+		if err != nil {
+			return unconfirmed(err)
+		}
+		d.Status = status
+		if printed >= 0 {
+			after, err := c.odometer()
+			if err != nil {
+				return unconfirmed(err)
+			}
+			// the formats buffered before the job are printed first
+			if after > printed+before.FormatsInBuffer {
+				return JobPrinted, nil
+			}
+		}
+		if status.FormatsInBuffer > before.FormatsInBuffer {
+			return JobQueued, nil
+		}
+		if !time.Now().Before(end) {
+			return JobSent, p.err("read", ErrUnconfirmed)
+		}
+		if err := sleep(ctx, poll); err != nil {
+			return unconfirmed(err)
+		}
+	}
+}
+
+// odometer reads the number of printed labels, -1 if the printer doesn't
+// know it
+func (c *conn) odometer() (int, error) {
+	value, err := c.getVar(odometerVar)
+	if err != nil {
+		return -1, err
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return -1, nil
+	}
+	return n, nil
+}
+
+// waitPrinted polls the status of the printer until its buffer is empty or
+// PrintTimeout expires. Errors keep the job queued.
+func (p *Printer) waitPrinted(ctx context.Context, d *Delivery, opts DeliveryOptions) {
+	ctx, cancel := context.WithTimeout(ctx, opts.PrintTimeout)
+	defer cancel()
+	for {
+		if err := sleep(ctx, opts.PollInterval); err != nil {
+			return
+		}
+		status, err := p.Status(ctx)
+		if err != nil {
+			continue
+		}
+		d.Status = status
+		if status.FormatsInBuffer == 0 {
+			d.State = JobPrinted
+			return
+		}
+	}
+}
+
+// sleep pauses for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,155 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/PaackEng/zplgfa/printer/printertest"
+)
+
+func newTestServer(t *testing.T) (*printertest.Server, *Printer) {
+	t.Helper()
+	s := printertest.NewServer()
+	t.Cleanup(s.Close)
+	p := New(s.Addr)
+	p.ReadTimeout = time.Second
+	return s, p
+}
+
+var testDelivery = DeliveryOptions{Backoff: 10 * time.Millisecond, PollInterval: 10 * time.Millisecond}
+
+func TestDeliver(t *testing.T) {
+	s, p := newTestServer(t)
+	opts := testDelivery
+	opts.PrintTimeout = time.Second
+	d, err := p.Deliver(context.Background(), "^XA^FDlabel^FS^XZ", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != JobPrinted || d.Attempts != 1 || d.Status == nil {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if s.Printed() != 1 {
+		t.Fatalf("printed %d labels", s.Printed())
+	}
+
+	// without formats, there's nothing to confirm
+	if d, err := p.Deliver(context.Background(), "~JA", opts); err != nil || d.State != JobSent {
+		t.Fatalf("Deliver = %+v, %v", d, err)
+	}
+}
+
+func TestDeliverQueued(t *testing.T) {
+	s, p := newTestServer(t)
+	s.SetPaused(true)
+	opts := testDelivery
+	opts.PrintTimeout = 100 * time.Millisecond
+	d, err := p.Deliver(context.Background(), "^XA^XZ", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != JobQueued || !d.Status.Paused || d.Status.FormatsInBuffer != 1 {
+		t.Fatalf("unexpected delivery %+v, status %+v", d, d.Status)
+	}
+}
+
+func TestDeliverPrinted(t *testing.T) {
+	s, p := newTestServer(t)
+	s.SetPrintDelay(0)
+	d, err := p.Deliver(context.Background(), "^XA^XZ", testDelivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != JobPrinted || d.Attempts != 1 {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if s.Printed() != 1 || s.Var("odometer.total_label_count") != "1" {
+		t.Fatalf("printed %d labels", s.Printed())
+	}
+
+	// without the label counter, a label printed right away isn't confirmed,
+	// but it isn't sent again either
+	s.SetVar("odometer.total_label_count", "?")
+	p.ReadTimeout = 100 * time.Millisecond
+	d, err = p.Deliver(context.Background(), "^XA^XZ", testDelivery)
+	if !errors.Is(err, ErrUnconfirmed) || d.State != JobSent || d.Attempts != 1 {
+		t.Fatalf("Deliver = %+v, %v", d, err)
+	}
+	if s.Printed() != 2 || len(s.Jobs()) != 2 {
+		t.Fatalf("printed %d labels of %d jobs", s.Printed(), len(s.Jobs()))
+	}
+}
+
+func TestDeliverBuffered(t *testing.T) {
+	s, p := newTestServer(t)
+	s.SetPrintDelay(300 * time.Millisecond)
+	s.SetDelay(200 * time.Millisecond)
+	if err := p.Send(context.Background(), "^XA^FDfirst^FS^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	// the printer stops after the format buffered before the job, which is
+	// printed while the job is sent
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		for s.Printed() == 0 {
+			select {
+			case <-done:
+				return
+			case <-time.After(time.Millisecond):
+			}
+		}
+		s.SetPaused(true)
+	}()
+
+	d, err := p.Deliver(context.Background(), "^XA^FDsecond^FS^XZ", testDelivery)
+	if !errors.Is(err, ErrUnconfirmed) || d.State != JobSent {
+		t.Fatalf("Deliver = %+v, %v", d, err)
+	}
+	if s.Printed() != 1 || len(s.Jobs()) != 2 {
+		t.Fatalf("printed %d labels of %d jobs", s.Printed(), len(s.Jobs()))
+	}
+}
+
+func TestDeliverRetry(t *testing.T) {
+	s, p := newTestServer(t)
+	s.InjectFault(printertest.DropConnection, 1)
+	d, err := p.Deliver(context.Background(), "^XA^XZ", testDelivery)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.State != JobQueued || d.Attempts != 2 {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+	if jobs := s.Jobs(); len(jobs) != 1 {
+		t.Fatalf("expected a single job, got %q", jobs)
+	}
+}
+
+func TestDeliverFailed(t *testing.T) {
+	s, p := newTestServer(t)
+	s.SetFault(printertest.DropConnection)
+	d, err := p.Deliver(context.Background(), "^XA^XZ", DeliveryOptions{Attempts: 3, Backoff: time.Millisecond})
+	var perr *Error
+	if !errors.As(err, &perr) {
+		t.Fatalf("expected a printer error, got %v", err)
+	}
+	if d.State != JobFailed || d.Attempts != 3 || d.Err != err {
+		t.Fatalf("unexpected delivery %+v", d)
+	}
+
+	// the job arrives, but isn't counted in the buffer
+	p = serve(t, respond(map[string]string{"~HS": hostStatus}))
+	p.ReadTimeout = 50 * time.Millisecond
+	if _, err := p.Deliver(context.Background(), "^XA^XZ", DeliveryOptions{Attempts: 1, PollInterval: 10 * time.Millisecond}); !errors.Is(err, ErrUnconfirmed) {
+		t.Fatalf("expected an unconfirmed delivery, got %v", err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if d, err := p.Deliver(ctx, "^XA^XZ", testDelivery); !errors.Is(err, context.Canceled) || d.Attempts != 1 {
+		t.Fatalf("Deliver = %+v, %v", d, err)
+	}
+}
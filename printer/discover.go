@@ -0,0 +1,243 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiscoveryPort is the UDP port printers answer discovery requests on
+const DiscoveryPort = 4201
+
+// DefaultDiscoveryTimeout limits discoveries if the context has no deadline
+const DefaultDiscoveryTimeout = 3 * time.Second
+
+// DiscoveryAddrs are the broadcast and the multicast address discovery
+// requests are sent to by default
+var DiscoveryAddrs = []string{"255.255.255.255:4201", "224.0.1.55:4201"}
+
+// discoveryRequest asks every printer receiving it for its discovery response
+var discoveryRequest = []byte{0x2e, 0x2c, 0x3a, 0x01, 0x00, 0x00}
+
+// Layout of the discovery response, the offsets and lengths of its fields
+const (
+	discoveryModel    = 12 // product name, 20 bytes
+	discoveryFirmware = 39 // 10 bytes
+	discoveryMAC      = 54 // 6 bytes
+	discoverySerial   = 60 // 10 bytes
+	discoveryName     = 80 // system name, 25 bytes
+	discoveryIP       = 105
+	discoveryLength   = 109
+)
+
+// DiscoveredPrinter is a printer found by Discover or Scan
+type DiscoveredPrinter struct {
+	IP       net.IP
+	Model    string
+	Serial   string
+	Name     string
+	Firmware string
+	MAC      net.HardwareAddr
+}
+
+// Printer returns a Printer for the discovered printer at the raw TCP port
+func (d *DiscoveredPrinter) Printer(port string) *Printer {
+	return New(net.JoinHostPort(d.IP.String(), port))
+}
+
+// field returns the text of a NUL padded field of the discovery response
+func field(response []byte, offset, length int) string {
+	b := response[offset : offset+length]
+	if i := bytes.IndexByte(b, 0); i >= 0 {
+		b = b[:i]
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// ParseDiscoveryResponse parses the UDP packet a printer answers a
+// discovery request with
+func ParseDiscoveryResponse(response []byte) (*DiscoveredPrinter, error) {
+	if len(response) < discoveryLength || !bytes.HasPrefix(response, discoveryRequest[:3]) {
+		return nil, fmt.Errorf("discovery: unexpected response of %d bytes", len(response))
+	}
+	ip := net.IP(append([]byte(nil), response[discoveryIP:discoveryIP+4]...))
+	return &DiscoveredPrinter{
+		IP:       ip,
+		Model:    field(response, discoveryModel, 20),
+		Serial:   field(response, discoverySerial, 10),
+		Name:     field(response, discoveryName, 25),
+		Firmware: field(response, discoveryFirmware, 10),
+		MAC:      net.HardwareAddr(append([]byte(nil), response[discoveryMAC:discoveryMAC+6]...)),
+	}, nil
+}
+
+// discoveryContext applies DefaultDiscoveryTimeout if ctx has no deadline
+func discoveryContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	if _, ok := ctx.Deadline(); ok {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, DefaultDiscoveryTimeout)
+}
+
+// Discover sends a discovery request to addrs (DiscoveryAddrs if none are
+// given) and collects the responses until ctx is done. A printer answering
+// several requests is listed once.
+func Discover(ctx context.Context, addrs ...string) ([]DiscoveredPrinter, error) {
+	if len(addrs) == 0 {
+		addrs = DiscoveryAddrs
+	}
+	ctx, cancel := discoveryContext(ctx)
+	defer cancel()
+
+	c, err := net.ListenUDP("udp4", nil)
+	if err != nil {
+		return nil, fmt.Errorf("discovery: %w", err)
+	}
+	defer c.Close()
+	go func() {
+		<-ctx.Done()
+		c.SetReadDeadline(time.Unix(1, 0))
+	}()
+
+	var sent bool
+	var sendErr error
+	for _, addr := range addrs {
+		udpAddr, err := net.ResolveUDPAddr("udp4", addr)
+		if err == nil {
+			_, err = c.WriteToUDP(discoveryRequest, udpAddr)
+		}
+		if err != nil {
+			sendErr = err
+			continue
+		}
+		sent = true
+	}
+	if !sent {
+		return nil, fmt.Errorf("discovery: %w", sendErr)
+	}
+
+	var found []DiscoveredPrinter
+	seen := map[string]bool{}
+	buf := make([]byte, 1500)
+	for {
+		n, from, err := c.ReadFromUDP(buf)
+		if err != nil {
+			var ne net.Error
+			if errors.As(err, &ne) && ne.Timeout() && ctx.Err() != nil {
+				return found, nil
+			}
+			return found, fmt.Errorf("discovery: %w", err)
+		}
+		d, err := ParseDiscoveryResponse(buf[:n])
+		if err != nil {
+			continue
+		}
+		if d.IP.IsUnspecified() {
+			d.IP = from.IP
+		}
+		if !seen[d.IP.String()] {
+			seen[d.IP.String()] = true
+			found = append(found, *d)
+		}
+	}
+}
+
+// maxScanHosts limits the subnets Scan accepts to a /16
+const maxScanHosts = 1 << 16
+
+// scanWorkers is the number of hosts Scan probes in parallel
+const scanWorkers = 64
+
+// subnetHosts lists the host addresses of the IPv4 subnet cidr, without the
+// network and broadcast address for subnets larger than a /31
+func subnetHosts(cidr string) ([]net.IP, error) {
+	ip, ipnet, err := net.ParseCIDR(cidr)
+	if err != nil {
+		return nil, err
+	}
+	if ip.To4() == nil {
+		return nil, fmt.Errorf("%s isn't an IPv4 subnet", cidr)
+	}
+	ones, bits := ipnet.Mask.Size()
+	size := 1 << (bits - ones)
+	if size > maxScanHosts {
+		return nil, fmt.Errorf("subnet %s is larger than a /16", cidr)
+	}
+
+	first := binary.BigEndian.Uint32(ipnet.IP.To4())
+	var hosts []net.IP
+	for i := 0; i < size; i++ {
+		if size > 2 && (i == 0 || i == size-1) {
+			continue
+		}
+		host := make(net.IP, 4)
+		binary.BigEndian.PutUint32(host, first+uint32(i))
+		hosts = append(hosts, host)
+	}
+	return hosts, nil
+}
+
+// Scan probes every host of the IPv4 subnet cidr (e.g. "192.168.178.0/24")
+// for a printer at the raw TCP port. Printers answering ~HI are listed with
+// their model and firmware, name and serial are queried via SGD if the
+// printer supports it. timeout limits connecting to and waiting for every host.
+func Scan(ctx context.Context, cidr, port string, timeout time.Duration) ([]DiscoveredPrinter, error) {
+	hosts, err := subnetHosts(cidr)
+	if err != nil {
+		return nil, fmt.Errorf("scan: %w", err)
+	}
+
+	var mu sync.Mutex
+	var found []DiscoveredPrinter
+	ips := make(chan net.IP)
+	var wg sync.WaitGroup
+	for i := 0; i < scanWorkers && i < len(hosts); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for ip := range ips {
+				if d := probe(ctx, ip, port, timeout); d != nil {
+					mu.Lock()
+					found = append(found, *d)
+					mu.Unlock()
+				}
+			}
+		}()
+	}
+	for _, ip := range hosts {
+		if ctx.Err() != nil {
+			break
+		}
+		ips <- ip
+	}
+	close(ips)
+	wg.Wait()
+
+	sort.Slice(found, func(i, j int) bool {
+		return bytes.Compare(found[i].IP, found[j].IP) < 0
+	})
+	return found, ctx.Err()
+}
+
+// probe returns the printer at ip, or nil if there is none
+func probe(ctx context.Context, ip net.IP, port string, timeout time.Duration) *DiscoveredPrinter {
+	p := New(net.JoinHostPort(ip.String(), port))
+	p.DialTimeout, p.ReadTimeout = timeout, timeout
+
+	id, err := p.Identify(ctx)
+	if err != nil {
+		return nil
+	}
+	d := &DiscoveredPrinter{IP: ip, Model: id.Model, Firmware: id.Firmware}
+	if values, err := p.GetVars(ctx, "device.friendly_name", "device.unique_id"); err == nil {
+		d.Name, d.Serial = values["device.friendly_name"], values["device.unique_id"]
+	}
+	return d
+}
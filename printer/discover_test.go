@@ -0,0 +1,120 @@
+package printer
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// discoveryResponse builds the discovery response of a printer
+func discoveryResponse(d DiscoveredPrinter) []byte {
+	response := make([]byte, discoveryLength+20)
+	copy(response, discoveryRequest[:3])
+	copy(response[discoveryModel:], d.Model)
+	copy(response[discoveryFirmware:], d.Firmware)
+	copy(response[discoveryMAC:], d.MAC)
+	copy(response[discoverySerial:], d.Serial)
+	copy(response[discoveryName:], d.Name)
+	copy(response[discoveryIP:], d.IP.To4())
+	return response
+}
+
+// discoveryResponder answers every discovery request on a local UDP port
+// with the responses of the printers
+func discoveryResponder(t *testing.T, printers ...DiscoveredPrinter) string {
+	t.Helper()
+	c, err := net.ListenUDP("udp4", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { c.Close() })
+	go func() {
+		buf := make([]byte, 64)
+		for {
+			n, from, err := c.ReadFromUDP(buf)
+			if err != nil {
+				return
+			}
+			if !reflect.DeepEqual(buf[:n], discoveryRequest) {
+				continue
+			}
+			c.WriteToUDP([]byte("garbage"), from)
+			for _, d := range printers {
+				c.WriteToUDP(discoveryResponse(d), from)
+			}
+		}
+	}()
+	return c.LocalAddr().String()
+}
+
+func TestDiscover(t *testing.T) {
+	zt410 := DiscoveredPrinter{
+		IP:       net.IP{192, 168, 178, 42},
+		Model:    "ZT410-203dpi",
+		Serial:   "18J194601",
+		Name:     "warehouse-1",
+		Firmware: "V75.20.01Z",
+		MAC:      net.HardwareAddr{0x00, 0x07, 0x4d, 0x11, 0x22, 0x33},
+	}
+	noIP := DiscoveredPrinter{IP: net.IPv4zero.To4(), Model: "ZD420", MAC: make(net.HardwareAddr, 6)}
+	addr := discoveryResponder(t, zt410, noIP)
+	// a second request to the same responder mustn't list the printers twice
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	found, err := Discover(ctx, addr, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(found) != 2 {
+		t.Fatalf("expected 2 printers, found %+v", found)
+	}
+	if !reflect.DeepEqual(found[0], zt410) {
+		t.Fatalf("found %+v, want %+v", found[0], zt410)
+	}
+	if !found[1].IP.Equal(net.IPv4(127, 0, 0, 1)) {
+		t.Fatalf("expected the sender address for a response without IP, got %v", found[1].IP)
+	}
+}
+
+func TestScan(t *testing.T) {
+	p := serve(t, respond(map[string]string{
+		"~HI":                                "\x02ZT410-203dpi,V75.20.01Z,8,8192KB\x03\r\n",
+		`! U1 getvar "device.friendly_name"`: `"warehouse-1"`,
+		`! U1 getvar "device.unique_id"`:     `"18J194601"`,
+	}))
+	_, port, _ := net.SplitHostPort(p.Addr)
+	found, err := Scan(context.Background(), "127.0.0.0/30", port, 500*time.Millisecond)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []DiscoveredPrinter{{
+		IP:       net.IP{127, 0, 0, 1},
+		Model:    "ZT410-203dpi",
+		Serial:   "18J194601",
+		Name:     "warehouse-1",
+		Firmware: "V75.20.01Z",
+	}}
+	if !reflect.DeepEqual(found, want) {
+		t.Fatalf("found %+v, want %+v", found, want)
+	}
+}
+
+func TestSubnetHosts(t *testing.T) {
+	for cidr, count := range map[string]int{
+		"192.168.178.0/24": 254,
+		"10.0.0.8/31":      2,
+		"10.0.0.8/32":      1,
+	} {
+		hosts, err := subnetHosts(cidr)
+		if err != nil || len(hosts) != count {
+			t.Errorf("%s: expected %d hosts, got %d (%v)", cidr, count, len(hosts), err)
+		}
+	}
+	for _, cidr := range []string{"10.0.0.0/8", "fe80::/120", "10.0.0.0"} {
+		if _, err := subnetHosts(cidr); err == nil {
+			t.Errorf("%s: expected an error", cidr)
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package printer
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Identity describes a printer as reported by ~HI (Host Identification)
+type Identity struct {
+	Model     string
+	Firmware  string
+	DotsPerMM int
+	MemoryKB  int
+	// Options lists the recognizable options, like a cutter or RFID
+	Options string
+}
+
+// DPI returns the resolution of the printer in dots per inch
+func (id *Identity) DPI() int {
+	switch id.DotsPerMM {
+	case 6:
+		return 152
+	case 8:
+		return 203
+	case 12:
+		return 300
+	case 24:
+		return 600
+	}
+	return int(math.Round(float64(id.DotsPerMM) * 25.4))
+}
+
+// ParseIdentity parses the response of a printer to ~HI
+func ParseIdentity(response string) (*Identity, error) {
+	fields := responseFields(response)
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("host identification: unexpected response %q", response)
+	}
+	fp := &fieldParser{fields: fields}
+	id := &Identity{
+		Model:     strings.TrimSpace(fields[0]),
+		Firmware:  strings.TrimSpace(fields[1]),
+		DotsPerMM: fp.int(2),
+	}
+	fp.fields[3] = strings.TrimSuffix(strings.TrimSpace(fields[3]), "KB")
+	id.MemoryKB = fp.int(3)
+	if fp.err != nil {
+		return nil, fmt.Errorf("host identification: invalid field: %w", fp.err)
+	}
+	if len(fields) > 4 {
+		id.Options = strings.TrimSpace(fields[4])
+	}
+	return id, nil
+}
+
+// Identify queries model, firmware, resolution and memory of the printer via ~HI
+func (p *Printer) Identify(ctx context.Context) (*Identity, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if err := c.send("~HI"); err != nil {
+		return nil, err
+	}
+	response, err := c.readLine()
+	if err != nil {
+		return nil, err
+	}
+	return ParseIdentity(response)
+}
+
+// ErrorFlags are the errors reported by ~HQES
+type ErrorFlags uint64
+
+// Error flags
+const (
+	MediaOut ErrorFlags = 1 << iota
+	RibbonOut
+	HeadOpen
+	CutterFault
+	HeadOverTemperature
+	MotorOverTemperature
+	BadHeadElement
+	HeadDetectionError
+	InvalidFirmwareConfig
+	HeadThermistorOpen
+	ClearPaperPathFailed
+	PaperFeedError
+	PresenterNotRunning
+	PaperJamDuringRetract
+	BlackMarkNotFound
+	BlackMarkCalibrateError
+	RetractTimeout
+	Paused
+)
+
+var errorNames = []string{
+	"media out", "ribbon out", "head open", "cutter fault",
+	"head over temperature", "motor over temperature", "bad head element",
+	"head detection error", "invalid firmware config", "head thermistor open",
+	"clear paper path failed", "paper feed error", "presenter not running",
+	"paper jam during retract", "black mark not found",
+	"black mark calibrate error", "retract timeout", "paused",
+}
+
+// WarningFlags are the warnings reported by ~HQES
+type WarningFlags uint64
+
+// Warning flags
+const (
+	NeedToCalibrateMedia WarningFlags = 1 << iota
+	CleanPrintHead
+	ReplacePrintHead
+	PaperNearEnd
+	Sensor1
+	Sensor2
+	Sensor3
+	Sensor4
+	Sensor5
+	Sensor6
+	Sensor7
+	Sensor8
+)
+
+var warningNames = []string{
+	"need to calibrate media", "clean print head", "replace print head",
+	"paper near end", "sensor 1", "sensor 2", "sensor 3", "sensor 4",
+	"sensor 5", "sensor 6", "sensor 7", "sensor 8",
+}
+
+// flagNames returns the names of the bits set in flags, unknown bits are
+// listed by their value
+func flagNames(flags uint64, names []string) []string {
+	var set []string
+	for bit := 0; bit < 64; bit++ {
+		if flags&(1<<bit) == 0 {
+			continue
+		}
+		if bit < len(names) {
+			set = append(set, names[bit])
+		} else {
+			set = append(set, fmt.Sprintf("0x%X", uint64(1)<<bit))
+		}
+	}
+	return set
+}
+
+// Names returns the names of all set error flags
+func (f ErrorFlags) Names() []string {
+	return flagNames(uint64(f), errorNames)
+}
+
+func (f ErrorFlags) String() string {
+	return strings.Join(f.Names(), ", ")
+}
+
+// Names returns the names of all set warning flags
+func (f WarningFlags) Names() []string {
+	return flagNames(uint64(f), warningNames)
+}
+
+func (f WarningFlags) String() string {
+	return strings.Join(f.Names(), ", ")
+}
+
+// ErrorStatus holds the error and warning flags of ~HQES (Host Query Error Status)
+type ErrorStatus struct {
+	Errors   ErrorFlags
+	Warnings WarningFlags
+}
+
+// parseFlagLine parses a line like "ERRORS:  1 00000000 00000005", the flags
+// are the two hexadecimal groups
+func parseFlagLine(line string) (uint64, error) {
+	fields := strings.Fields(line[strings.Index(line, ":")+1:])
+	if len(fields) < 3 {
+		return 0, fmt.Errorf("unexpected line %q", line)
+	}
+	flags, err := strconv.ParseUint(fields[1]+fields[2], 16, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid flags in line %q: %w", line, err)
+	}
+	return flags, nil
+}
+
+// ParseErrorStatus parses the response of a printer to ~HQES
+func ParseErrorStatus(response string) (*ErrorStatus, error) {
+	var status ErrorStatus
+	var foundErrors, foundWarnings bool
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(line, "ERRORS:"):
+			flags, err := parseFlagLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("error status: %w", err)
+			}
+			status.Errors, foundErrors = ErrorFlags(flags), true
+		case strings.HasPrefix(line, "WARNINGS:"):
+			flags, err := parseFlagLine(line)
+			if err != nil {
+				return nil, fmt.Errorf("error status: %w", err)
+			}
+			status.Warnings, foundWarnings = WarningFlags(flags), true
+		}
+	}
+	if !foundErrors || !foundWarnings {
+		return nil, fmt.Errorf("error status: unexpected response %q", response)
+	}
+	return &status, nil
+}
+
+// Errors queries the error and warning flags of the printer via ~HQES
+func (p *Printer) Errors(ctx context.Context) (*ErrorStatus, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if err := c.send("~HQES"); err != nil {
+		return nil, err
+	}
+	response, err := c.readFramed()
+	if err != nil {
+		return nil, err
+	}
+	return ParseErrorStatus(response)
+}
@@ -0,0 +1,67 @@
+package printer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const hostQueryErrorStatus = "\x02\r\n  PRINTER STATUS\r\n" +
+	"   ERRORS:         1 00000000 00000005\r\n" +
+	"   WARNINGS:       1 00000000 00000002\r\n\x03\r\n"
+
+func TestParseIdentity(t *testing.T) {
+	id, err := ParseIdentity("\x02ZT410-300dpi,V75.20.01Z,12,8192KB,C\x03\r\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &Identity{Model: "ZT410-300dpi", Firmware: "V75.20.01Z", DotsPerMM: 12, MemoryKB: 8192, Options: "C"}
+	if !reflect.DeepEqual(id, want) {
+		t.Fatalf("ParseIdentity = %+v, want %+v", id, want)
+	}
+	if id.DPI() != 300 {
+		t.Fatalf("DPI = %d, want 300", id.DPI())
+	}
+	for _, invalid := range []string{"", "\x02ZT410,V75\x03", "\x02ZT410,V75,x,8192KB\x03"} {
+		if _, err := ParseIdentity(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestParseErrorStatus(t *testing.T) {
+	status, err := ParseErrorStatus(hostQueryErrorStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status.Errors != MediaOut|HeadOpen || status.Warnings != CleanPrintHead {
+		t.Fatalf("unexpected status %+v", status)
+	}
+	if got := status.Errors.String(); got != "media out, head open" {
+		t.Fatalf("unexpected error names %q", got)
+	}
+	if got := ErrorFlags(1 << 40).Names(); !reflect.DeepEqual(got, []string{"0x10000000000"}) {
+		t.Fatalf("unexpected names of unknown flags %v", got)
+	}
+	if _, err := ParseErrorStatus("PRINTER STATUS\r\n ERRORS: 1 0000000X 00000000\r\n"); err == nil {
+		t.Fatal("expected an error for invalid flags")
+	}
+	if _, err := ParseErrorStatus("PRINTER STATUS\r\n"); err == nil {
+		t.Fatal("expected an error for missing flags")
+	}
+}
+
+func TestIdentifyAndErrors(t *testing.T) {
+	p := serve(t, respond(map[string]string{
+		"~HI":   "\x02ZD420-203dpi,V84.20.18Z,8,4096KB\x03\r\n",
+		"~HQES": hostQueryErrorStatus,
+	}))
+	id, err := p.Identify(context.Background())
+	if err != nil || id.Model != "ZD420-203dpi" || id.DPI() != 203 {
+		t.Fatalf("unexpected identity %+v, %v", id, err)
+	}
+	status, err := p.Errors(context.Background())
+	if err != nil || status.Errors != MediaOut|HeadOpen {
+		t.Fatalf("unexpected error status %+v, %v", status, err)
+	}
+}
@@ -0,0 +1,183 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// Document formats of IPP jobs
+const (
+	FormatZPL = "application/vnd.zebra-zpl"
+	FormatRaw = "application/octet-stream"
+)
+
+// IPP value tags and delimiters, see RFC 8010
+const (
+	ippOperationAttributes = 0x01
+	ippEndOfAttributes     = 0x03
+	ippText                = 0x41
+	ippName                = 0x42
+	ippURI                 = 0x45
+	ippCharset             = 0x47
+	ippNaturalLanguage     = 0x48
+	ippMimeMediaType       = 0x49
+
+	ippPrintJob = 0x0002
+)
+
+// IPP is a Transport submitting every job via IPP Print-Job to a print
+// server like CUPS. Responses can't be read.
+type IPP struct {
+	// URL of the printer, e.g. "ipp://cups.local:631/printers/zebra",
+	// ipps uses HTTPS
+	URL string
+	// Format is the document format, FormatZPL if empty. Print servers
+	// which don't know ZPL often accept FormatRaw.
+	Format string
+	// User is the requesting user name, "zplgfa" if empty
+	User string
+	// Client sends the requests, http.DefaultClient if nil
+	Client *http.Client
+}
+
+// Open returns a connection which submits the written data as a job when closed
+func (i IPP) Open(ctx context.Context) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if _, err := i.httpURL(); err != nil {
+		return nil, err
+	}
+	return &jobConn{submit: i.printJob}, nil
+}
+
+// NewIPP returns a Printer submitting jobs to the IPP printer at url with the
+// default timeouts
+func NewIPP(url string) *Printer {
+	p := New(url)
+	p.Transport = IPP{URL: url}
+	return p
+}
+
+// httpURL returns the URL of the printer as HTTP URL
+func (i IPP) httpURL() (string, error) {
+	switch {
+	case strings.HasPrefix(i.URL, "ipp://"):
+		return "http://" + strings.TrimPrefix(i.URL, "ipp://"), nil
+	case strings.HasPrefix(i.URL, "ipps://"):
+		return "https://" + strings.TrimPrefix(i.URL, "ipps://"), nil
+	case strings.HasPrefix(i.URL, "http://"), strings.HasPrefix(i.URL, "https://"):
+		return i.URL, nil
+	}
+	return "", fmt.Errorf("ipp: unsupported URL %q", i.URL)
+}
+
+// ippAttribute appends an attribute to the request
+func ippAttribute(buf *bytes.Buffer, tag byte, name, value string) {
+	buf.WriteByte(tag)
+	binary.Write(buf, binary.BigEndian, uint16(len(name)))
+	buf.WriteString(name)
+	binary.Write(buf, binary.BigEndian, uint16(len(value)))
+	buf.WriteString(value)
+}
+
+// printJob submits data via Print-Job
+func (i IPP) printJob(ctx context.Context, data []byte) error {
+	url, err := i.httpURL()
+	if err != nil {
+		return err
+	}
+	format, user := i.Format, i.User
+	if format == "" {
+		format = FormatZPL
+	}
+	if user == "" {
+		user = "zplgfa"
+	}
+
+	var req bytes.Buffer
+	req.Write([]byte{2, 0}) // version 2.0
+	binary.Write(&req, binary.BigEndian, uint16(ippPrintJob))
+	binary.Write(&req, binary.BigEndian, uint32(1)) // request id
+	req.WriteByte(ippOperationAttributes)
+	ippAttribute(&req, ippCharset, "attributes-charset", "utf-8")
+	ippAttribute(&req, ippNaturalLanguage, "attributes-natural-language", "en")
+	ippAttribute(&req, ippURI, "printer-uri", i.URL)
+	ippAttribute(&req, ippName, "requesting-user-name", user)
+	ippAttribute(&req, ippName, "job-name", "zplgfa")
+	ippAttribute(&req, ippMimeMediaType, "document-format", format)
+	req.WriteByte(ippEndOfAttributes)
+	req.Write(data)
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, &req)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/ipp")
+	client := i.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("ipp: print server responded %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	return parseIPPResponse(body)
+}
+
+// parseIPPResponse checks the status code of an IPP response, the status
+// message is part of the error if the server sent one
+func parseIPPResponse(resp []byte) error {
+	if len(resp) < 8 {
+		return errors.New("ipp: response too short")
+	}
+	status := binary.BigEndian.Uint16(resp[2:4])
+	if status < 0x0100 {
+		return nil
+	}
+
+	var message string
+	for rest := resp[8:]; len(rest) > 0 && rest[0] != ippEndOfAttributes; {
+		if rest[0] < 0x10 {
+			// delimiter of the next attribute group
+			rest = rest[1:]
+			continue
+		}
+		if len(rest) < 3 {
+			break
+		}
+		tag := rest[0]
+		nameLen := int(binary.BigEndian.Uint16(rest[1:3]))
+		if len(rest) < 5+nameLen {
+			break
+		}
+		name := string(rest[3 : 3+nameLen])
+		valueLen := int(binary.BigEndian.Uint16(rest[3+nameLen:]))
+		if len(rest) < 5+nameLen+valueLen {
+			break
+		}
+		value := rest[5+nameLen : 5+nameLen+valueLen]
+		if name == "status-message" && tag == ippText {
+			message = string(value)
+		}
+		rest = rest[5+nameLen+valueLen:]
+	}
+	if message != "" {
+		return fmt.Errorf("ipp: Print-Job failed with status 0x%04x: %s", status, message)
+	}
+	return fmt.Errorf("ipp: Print-Job failed with status 0x%04x", status)
+}
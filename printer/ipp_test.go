@@ -0,0 +1,94 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// ippRequest is a decoded IPP request
+type ippRequest struct {
+	operation  uint16
+	attributes map[string]string
+	data       string
+}
+
+func decodeIPPRequest(t *testing.T, body []byte) ippRequest {
+	t.Helper()
+	req := ippRequest{operation: binary.BigEndian.Uint16(body[2:4]), attributes: map[string]string{}}
+	rest := body[8:]
+	for rest[0] != ippEndOfAttributes {
+		if rest[0] < 0x10 {
+			rest = rest[1:]
+			continue
+		}
+		nameLen := int(binary.BigEndian.Uint16(rest[1:3]))
+		name := string(rest[3 : 3+nameLen])
+		valueLen := int(binary.BigEndian.Uint16(rest[3+nameLen:]))
+		req.attributes[name] = string(rest[5+nameLen : 5+nameLen+valueLen])
+		rest = rest[5+nameLen+valueLen:]
+	}
+	req.data = string(rest[1:])
+	return req
+}
+
+// ippResponse encodes a response with the status code and message
+func ippResponse(status uint16, message string) []byte {
+	var buf bytes.Buffer
+	buf.Write([]byte{2, 0})
+	binary.Write(&buf, binary.BigEndian, status)
+	binary.Write(&buf, binary.BigEndian, uint32(1))
+	buf.WriteByte(ippOperationAttributes)
+	ippAttribute(&buf, ippCharset, "attributes-charset", "utf-8")
+	if message != "" {
+		ippAttribute(&buf, ippText, "status-message", message)
+	}
+	buf.WriteByte(ippEndOfAttributes)
+	return buf.Bytes()
+}
+
+func TestIPP(t *testing.T) {
+	requests := make(chan ippRequest, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		if r.Method != http.MethodPost || r.URL.Path != "/printers/zebra" || r.Header.Get("Content-Type") != "application/ipp" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		req := decodeIPPRequest(t, body)
+		requests <- req
+		if req.attributes["document-format"] != FormatZPL {
+			w.Write(ippResponse(0x040a, "document-format not supported"))
+			return
+		}
+		w.Write(ippResponse(0x0000, ""))
+	}))
+	defer server.Close()
+
+	url := "ipp://" + strings.TrimPrefix(server.URL, "http://") + "/printers/zebra"
+	p := NewIPP(url)
+	if err := p.Send(context.Background(), "^XA^FDipp^FS^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	req := <-requests
+	if req.operation != ippPrintJob || req.attributes["printer-uri"] != url || req.data != "^XA^FDipp^FS^XZ\r\n\r\n" {
+		t.Fatalf("unexpected request %+v", req)
+	}
+
+	p.Transport = IPP{URL: url, Format: FormatRaw}
+	err := p.Send(context.Background(), "^XA^XZ")
+	if <-requests; err == nil || !strings.Contains(err.Error(), "document-format not supported") {
+		t.Fatalf("expected the status message as error, got %v", err)
+	}
+	if _, err := p.Status(context.Background()); err == nil {
+		t.Fatal("expected an error for a query via IPP")
+	}
+	if err := NewIPP("lpd://host/queue").Send(context.Background(), "^XA^XZ"); err == nil {
+		t.Fatal("expected an error for an unsupported URL")
+	}
+}
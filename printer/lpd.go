@@ -0,0 +1,111 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// LPDPort is the TCP port of LPD print servers
+const LPDPort = "515"
+
+// lpdJobNumber counts the jobs, LPD uses three digit job numbers
+var lpdJobNumber uint32
+
+// LPD is a Transport submitting every job to a queue of an LPD print server
+// (RFC 1179). The job is printed unfiltered. Responses can't be read.
+type LPD struct {
+	// Addr is the host and port of the print server, the port defaults to 515
+	Addr  string
+	Queue string
+}
+
+// Open returns a connection which submits the written data as a job when closed
+func (l LPD) Open(ctx context.Context) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return &jobConn{submit: l.printJob}, nil
+}
+
+// NewLPD returns a Printer submitting jobs to the queue of the LPD print
+// server at addr with the default timeouts
+func NewLPD(addr, queue string) *Printer {
+	p := New(addr + "/" + queue)
+	p.Transport = LPD{Addr: addr, Queue: queue}
+	return p
+}
+
+// lpdCommand sends a command or file and waits for the acknowledgement
+func lpdCommand(w io.Writer, r *bufio.Reader, data string) error {
+	if _, err := io.WriteString(w, data); err != nil {
+		return err
+	}
+	ack, err := r.ReadByte()
+	if err != nil {
+		return err
+	}
+	if ack != 0 {
+		return fmt.Errorf("lpd: print server refused %q with %d", data[:1], ack)
+	}
+	return nil
+}
+
+// printJob submits data with a control file to the queue
+func (l LPD) printJob(ctx context.Context, data []byte) error {
+	addr := l.Addr
+	if _, _, err := net.SplitHostPort(addr); err != nil {
+		addr = net.JoinHostPort(addr, LPDPort)
+	}
+	var dialer net.Dialer
+	c, err := dialer.DialContext(ctx, "tcp", addr)
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		c.SetDeadline(deadline)
+	}
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblock pending reads and writes
+			c.SetDeadline(time.Unix(1, 0))
+		case <-done:
+		}
+	}()
+	r := bufio.NewReader(c)
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		host = "zplgfa"
+	}
+	if len(host) > 31 {
+		host = host[:31]
+	}
+	job := fmt.Sprintf("%03d%s", atomic.AddUint32(&lpdJobNumber, 1)%1000, host)
+	// l prints the data file without filtering control characters
+	control := fmt.Sprintf("H%s\nPzplgfa\nJzplgfa\nldfA%s\nUdfA%s\nNzplgfa\n", host, job, job)
+
+	// receive job, control file and data file
+	if err := lpdCommand(c, r, "\x02"+l.Queue+"\n"); err != nil {
+		return err
+	}
+	if err := lpdCommand(c, r, fmt.Sprintf("\x02%d cfA%s\n", len(control), job)); err != nil {
+		return err
+	}
+	if err := lpdCommand(c, r, control+"\x00"); err != nil {
+		return err
+	}
+	if err := lpdCommand(c, r, fmt.Sprintf("\x03%d dfA%s\n", len(data), job)); err != nil {
+		return err
+	}
+	return lpdCommand(c, r, string(data)+"\x00")
+}
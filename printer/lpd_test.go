@@ -0,0 +1,133 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// lpdJob is a job received by lpdServer
+type lpdJob struct {
+	queue   string
+	control string
+	data    string
+}
+
+// lpdServer is a minimal LPD print server, which accepts jobs for the queue
+func lpdServer(t *testing.T, queue string, jobs chan<- lpdJob) string {
+	t.Helper()
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				if job, err := receiveLPDJob(c, queue); err == nil {
+					jobs <- job
+				}
+			}()
+		}
+	}()
+	return l.Addr().String()
+}
+
+func receiveLPDJob(c net.Conn, queue string) (lpdJob, error) {
+	var job lpdJob
+	r := bufio.NewReader(c)
+	line, err := r.ReadString('\n')
+	if err != nil || line[0] != 2 {
+		return job, fmt.Errorf("expected a receive job command, got %q", line)
+	}
+	if job.queue = strings.TrimSpace(line[1:]); job.queue != queue {
+		c.Write([]byte{1})
+		return job, fmt.Errorf("unknown queue %q", job.queue)
+	}
+	c.Write([]byte{0})
+	for i := 0; i < 2; i++ {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return job, err
+		}
+		size, err := strconv.Atoi(strings.Fields(line[1:])[0])
+		if err != nil {
+			return job, err
+		}
+		c.Write([]byte{0})
+		file := make([]byte, size+1)
+		if _, err := io.ReadFull(r, file); err != nil || file[size] != 0 {
+			return job, fmt.Errorf("invalid file: %v", err)
+		}
+		c.Write([]byte{0})
+		if line[0] == 2 {
+			job.control = string(file[:size])
+		} else {
+			job.data = string(file[:size])
+		}
+	}
+	return job, nil
+}
+
+func TestLPD(t *testing.T) {
+	jobs := make(chan lpdJob, 1)
+	addr := lpdServer(t, "zebra", jobs)
+
+	if err := NewLPD(addr, "zebra").Send(context.Background(), "^XA^FDlpd^FS^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	job := <-jobs
+	if job.data != "^XA^FDlpd^FS^XZ\r\n\r\n" {
+		t.Fatalf("unexpected data file %q", job.data)
+	}
+	if !strings.Contains(job.control, "\nldfA") || !strings.HasPrefix(job.control, "H") {
+		t.Fatalf("unexpected control file %q", job.control)
+	}
+
+	err := NewLPD(addr, "unknown").Send(context.Background(), "^XA^XZ")
+	if perr, ok := err.(*Error); !ok || perr.Op != "write" || !strings.Contains(err.Error(), "refused") {
+		t.Fatalf("expected the refused job as error, got %v", err)
+	}
+}
+
+func TestLPDCanceled(t *testing.T) {
+	// a print server which never acknowledges
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			defer c.Close()
+		}
+	}()
+
+	p := NewLPD(l.Addr().String(), "zebra")
+	p.WriteTimeout = 0
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	start := time.Now()
+	if err := p.Send(ctx, "^XA^XZ"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the submission to be canceled, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("canceling took %v", elapsed)
+	}
+}
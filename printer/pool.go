@@ -0,0 +1,237 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Balancing selects the printer of a pool for the next job
+type Balancing int
+
+// Balancing strategies
+const (
+	// RoundRobin uses the printers in turn
+	RoundRobin Balancing = iota
+	// LeastQueued uses the printer with the fewest formats in its buffer,
+	// counting the jobs sent since its last status
+	LeastQueued
+)
+
+// ParseBalancing parses "roundrobin" or "leastqueued"
+func ParseBalancing(s string) (Balancing, error) {
+	switch strings.ToLower(s) {
+	case "", "roundrobin", "round-robin":
+		return RoundRobin, nil
+	case "leastqueued", "least-queued":
+		return LeastQueued, nil
+	}
+	return 0, fmt.Errorf("unknown balancing %q", s)
+}
+
+// DefaultCheckInterval is the interval of the health checks of a pool if not set
+const DefaultCheckInterval = 10 * time.Second
+
+// ErrNoPrinter is returned by a pool without healthy printers
+var ErrNoPrinter = errors.New("no healthy printer in the pool")
+
+// PoolMember is the state of a printer in a pool
+type PoolMember struct {
+	Printer *Printer
+	// Healthy printers get jobs
+	Healthy bool
+	// Status is the result of the last health check, nil if it failed
+	Status *HostStatus
+	// Queued is the number of formats in the buffer at the last health
+	// check plus the jobs sent since
+	Queued int
+	// Err is the error of the last health check or job, if it failed
+	Err error
+}
+
+// Pool spreads jobs across identical printers. Printers whose status
+// reports a problem or which fail a job are taken out of rotation until a
+// health check finds them ready again.
+type Pool struct {
+	Balancing Balancing
+	// CheckInterval is the interval of the health checks by Run
+	CheckInterval time.Duration
+
+	mu      sync.Mutex
+	members []*PoolMember
+	// next is the member to start the search for the next printer at
+	next int
+}
+
+// NewPool returns a pool of the printers, which are considered healthy
+// until checked
+func NewPool(balancing Balancing, printers ...*Printer) *Pool {
+	pool := &Pool{Balancing: balancing, CheckInterval: DefaultCheckInterval}
+	for _, p := range printers {
+		pool.members = append(pool.members, &PoolMember{Printer: p, Healthy: true})
+	}
+	return pool
+}
+
+// Members returns the state of the printers in the pool
+func (pool *Pool) Members() []PoolMember {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	members := make([]PoolMember, len(pool.members))
+	for i, m := range pool.members {
+		members[i] = *m
+	}
+	return members
+}
+
+// Check queries the status of all printers via ~HS and updates their health
+func (pool *Pool) Check(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, m := range pool.members {
+		wg.Add(1)
+		go func(m *PoolMember) {
+			defer wg.Done()
+			status, err := m.Printer.Status(ctx)
+			if interrupted(ctx) {
+				// an interrupted check says nothing about the printer
+				return
+			}
+			pool.mu.Lock()
+			defer pool.mu.Unlock()
+			m.Status, m.Err = status, err
+			if err != nil {
+				m.Healthy = false
+				return
+			}
+			m.Healthy = status.Ready()
+			m.Queued = status.FormatsInBuffer
+			if !m.Healthy {
+				m.Err = fmt.Errorf("printer %s: %s", m.Printer.Addr, strings.Join(status.Problems(), ", "))
+			}
+		}(m)
+	}
+	wg.Wait()
+}
+
+// interrupted reports whether ctx is done, including the moment its deadline
+// passed and operations fail with a timeout, but the context isn't done yet
+func interrupted(ctx context.Context) bool {
+	d, ok := ctx.Deadline()
+	return ctx.Err() != nil || ok && !time.Now().Before(d)
+}
+
+// Run checks the health of the printers right away and then every
+// CheckInterval until ctx is done
+func (pool *Pool) Run(ctx context.Context) error {
+	interval := pool.CheckInterval
+	if interval <= 0 {
+		interval = DefaultCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		pool.Check(ctx)
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// pick selects the next healthy printer, skipping the ones in tried
+func (pool *Pool) pick(tried map[*PoolMember]bool) *PoolMember {
+	pool.mu.Lock()
+	defer pool.mu.Unlock()
+	var picked *PoolMember
+	for i := range pool.members {
+		m := pool.members[(pool.next+i)%len(pool.members)]
+		if !m.Healthy || tried[m] {
+			continue
+		}
+		if picked == nil || pool.Balancing == LeastQueued && m.Queued < picked.Queued {
+			picked = m
+		}
+		if pool.Balancing == RoundRobin {
+			break
+		}
+	}
+	if picked == nil {
+		return nil
+	}
+	for i, m := range pool.members {
+		if m == picked {
+			pool.next = (i + 1) % len(pool.members)
+		}
+	}
+	picked.Queued++
+	return picked
+}
+
+// failed takes the printer out of rotation
+func (pool *Pool) failed(m *PoolMember, err error) {
+	pool.mu.Lock()
+	m.Healthy, m.Err = false, err
+	m.Queued--
+	pool.mu.Unlock()
+}
+
+// do runs job on the next healthy printer, and on the following ones as
+// long as they can't be connected to. Once written, even in part or without
+// confirmation, the job may have been printed, so other errors end the search
+// and return the printer.
+func (pool *Pool) do(ctx context.Context, job func(p *Printer) error) (*Printer, error) {
+	tried := map[*PoolMember]bool{}
+	err := ErrNoPrinter
+	for {
+		m := pool.pick(tried)
+		if m == nil {
+			return nil, err
+		}
+		tried[m] = true
+		if err = job(m.Printer); err == nil || errors.Is(err, ErrUnconfirmed) {
+			return m.Printer, err
+		}
+		if interrupted(ctx) {
+			return nil, err
+		}
+		pool.failed(m, err)
+		if !dialFailed(err) {
+			return m.Printer, err
+		}
+	}
+}
+
+// dialFailed reports whether err is the failure to connect to a printer, so
+// nothing reached it
+func dialFailed(err error) bool {
+	var perr *Error
+	return errors.As(err, &perr) && perr.Op == "dial"
+}
+
+// Send sends the ZPL data to the next healthy printer and returns it. If it
+// can't be connected to, the other healthy printers are tried. If sending
+// fails afterwards, the printer is returned with the error.
+func (pool *Pool) Send(ctx context.Context, zpl string) (*Printer, error) {
+	return pool.do(ctx, func(p *Printer) error {
+		return p.Send(ctx, zpl)
+	})
+}
+
+// Deliver delivers the ZPL data like Printer.Deliver to the next healthy
+// printer and returns it. If it can't be connected to, the other healthy
+// printers are tried. A job which may have reached the printer isn't sent to
+// another one: its printer is returned with the error, e.g. of a failed write
+// or wrapping ErrUnconfirmed.
+func (pool *Pool) Deliver(ctx context.Context, zpl string, opts DeliveryOptions) (*Printer, *Delivery, error) {
+	var delivery *Delivery
+	p, err := pool.do(ctx, func(p *Printer) error {
+		var err error
+		delivery, err = p.Deliver(ctx, zpl, opts)
+		return err
+	})
+	return p, delivery, err
+}
@@ -0,0 +1,187 @@
+package printer
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PaackEng/zplgfa/printer/printertest"
+)
+
+func newTestPool(t *testing.T, balancing Balancing, n int) ([]*printertest.Server, *Pool) {
+	t.Helper()
+	var servers []*printertest.Server
+	var printers []*Printer
+	for i := 0; i < n; i++ {
+		s, p := newTestServer(t)
+		servers = append(servers, s)
+		printers = append(printers, p)
+	}
+	return servers, NewPool(balancing, printers...)
+}
+
+// jobCounts returns the number of jobs received by every server once the
+// expected total arrived
+func jobCounts(t *testing.T, servers []*printertest.Server, total int) []int {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for {
+		var counts []int
+		sum := 0
+		for _, s := range servers {
+			counts = append(counts, len(s.Jobs()))
+			sum += len(s.Jobs())
+		}
+		if sum >= total || time.Now().After(deadline) {
+			return counts
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestPoolRoundRobin(t *testing.T) {
+	servers, pool := newTestPool(t, RoundRobin, 3)
+	ctx := context.Background()
+	for i := 0; i < 6; i++ {
+		if _, err := pool.Send(ctx, "^XA^XZ"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if counts := jobCounts(t, servers, 6); counts[0] != 2 || counts[1] != 2 || counts[2] != 2 {
+		t.Fatalf("jobs per printer %v", counts)
+	}
+
+	// printers with problems are skipped until they recover
+	servers[1].SetPaperOut(true)
+	pool.Check(ctx)
+	if m := pool.Members()[1]; m.Healthy || m.Err == nil || !m.Status.PaperOut {
+		t.Fatalf("expected the printer without paper to be unhealthy: %+v", m)
+	}
+	for i := 0; i < 4; i++ {
+		p, err := pool.Send(ctx, "^XA^XZ")
+		if err != nil || p.Addr == servers[1].Addr {
+			t.Fatalf("Send = %v, %v", p, err)
+		}
+	}
+	if counts := jobCounts(t, servers, 10); counts[0] != 4 || counts[1] != 2 || counts[2] != 4 {
+		t.Fatalf("jobs per printer %v", counts)
+	}
+	servers[1].SetPaperOut(false)
+	pool.Check(ctx)
+	if m := pool.Members()[1]; !m.Healthy || m.Err != nil {
+		t.Fatalf("expected the printer to be back: %+v", m)
+	}
+}
+
+func TestPoolLeastQueued(t *testing.T) {
+	servers, pool := newTestPool(t, LeastQueued, 3)
+	ctx := context.Background()
+	servers[0].SetPrintDelay(time.Minute)
+	servers[1].SetPrintDelay(time.Minute)
+	if err := pool.Members()[0].Printer.Send(ctx, "^XA^XZ^XA^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	if err := pool.Members()[1].Printer.Send(ctx, "^XA^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := servers[0].WaitJobs(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := servers[1].WaitJobs(ctx, 1); err != nil {
+		t.Fatal(err)
+	}
+	pool.Check(ctx)
+
+	// the jobs sent count until the next check
+	var got []string
+	for i := 0; i < 3; i++ {
+		p, err := pool.Send(ctx, "^XA^XZ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, p.Addr)
+	}
+	if want := []string{servers[2].Addr, servers[1].Addr, servers[2].Addr}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("printers %v, want %v", got, want)
+	}
+}
+
+func TestPoolFailover(t *testing.T) {
+	servers, pool := newTestPool(t, RoundRobin, 2)
+	ctx := context.Background()
+	servers[0].Close()
+	p, d, err := pool.Deliver(ctx, "^XA^XZ", DeliveryOptions{Attempts: 1})
+	if err != nil || p.Addr != servers[1].Addr || d.State != JobQueued {
+		t.Fatalf("Deliver = %v, %+v, %v", p, d, err)
+	}
+	if m := pool.Members()[0]; m.Healthy || m.Err == nil {
+		t.Fatalf("expected the failed printer to be unhealthy: %+v", m)
+	}
+
+	servers[1].SetFault(printertest.DropConnection)
+	var perr *Error
+	if _, _, err := pool.Deliver(ctx, "^XA^XZ", DeliveryOptions{Attempts: 1}); !errors.As(err, &perr) {
+		t.Fatalf("expected the error of the last printer, got %v", err)
+	}
+	if _, err := pool.Send(ctx, "^XA^XZ"); !errors.Is(err, ErrNoPrinter) {
+		t.Fatalf("expected no printer, got %v", err)
+	}
+
+	servers[1].SetFault(printertest.NoFault)
+	ctx, cancel := context.WithTimeout(ctx, 100*time.Millisecond)
+	defer cancel()
+	pool.CheckInterval = 10 * time.Millisecond
+	if err := pool.Run(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatal(err)
+	}
+	if members := pool.Members(); members[0].Healthy || !members[1].Healthy {
+		t.Fatalf("unexpected members %+v", members)
+	}
+}
+
+func TestPoolWriteFailed(t *testing.T) {
+	servers, pool := newTestPool(t, RoundRobin, 2)
+	// the data may reach the printer in part before the write fails, so it
+	// isn't sent to the next printer
+	pool.Members()[0].Printer.WriteTimeout = time.Nanosecond
+	var perr *Error
+	p, err := pool.Send(context.Background(), "^XA^XZ")
+	if !errors.As(err, &perr) || perr.Op != "write" || p == nil || p.Addr != servers[0].Addr {
+		t.Fatalf("Send = %v, %v", p, err)
+	}
+	if counts := jobCounts(t, servers, 1); counts[1] != 0 {
+		t.Fatalf("jobs per printer %v", counts)
+	}
+	if m := pool.Members()[0]; m.Healthy {
+		t.Fatalf("expected the failed printer to be unhealthy: %+v", m)
+	}
+}
+
+func TestPoolUnconfirmed(t *testing.T) {
+	servers, pool := newTestPool(t, RoundRobin, 2)
+	ctx := context.Background()
+	for _, s := range servers {
+		s.SetPrintDelay(0)
+	}
+	p, d, err := pool.Deliver(ctx, "^XA^XZ", testDelivery)
+	if err != nil || p.Addr != servers[0].Addr || d.State != JobPrinted {
+		t.Fatalf("Deliver = %v, %+v, %v", p, d, err)
+	}
+
+	// a label printed right away without a label counter stays unconfirmed,
+	// it mustn't be printed by the next printer as well
+	servers[1].SetVar("odometer.total_label_count", "?")
+	pool.Members()[1].Printer.ReadTimeout = 50 * time.Millisecond
+	p, d, err = pool.Deliver(ctx, "^XA^XZ", testDelivery)
+	if !errors.Is(err, ErrUnconfirmed) || p.Addr != servers[1].Addr || d.State != JobSent || d.Attempts != 1 {
+		t.Fatalf("Deliver = %v, %+v, %v", p, d, err)
+	}
+	if counts := jobCounts(t, servers, 2); counts[0] != 1 || counts[1] != 1 {
+		t.Fatalf("jobs per printer %v", counts)
+	}
+	if m := pool.Members()[1]; !m.Healthy {
+		t.Fatalf("expected the printer to stay healthy: %+v", m)
+	}
+}
@@ -0,0 +1,352 @@
+// Package printer sends ZPL to Zebra compatible label printers and queries
+// their state over the raw TCP port (usually 9100), device files or serial
+// ports.
+package printer
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+// Control characters framing the responses of the printers
+const (
+	stx = 0x02
+	etx = 0x03
+)
+
+// Default timeouts of printers created by New
+const (
+	DefaultDialTimeout  = 5 * time.Second
+	DefaultWriteTimeout = 30 * time.Second
+	DefaultReadTimeout  = 5 * time.Second
+	DefaultIdleTimeout  = 300 * time.Millisecond
+)
+
+// Error is returned by the Printer methods, it records the failed operation
+// and the address of the printer
+type Error struct {
+	// Op is the failed operation: "dial", "write" or "read"
+	Op   string
+	Addr string
+	Err  error
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("printer %s %s: %v", e.Op, e.Addr, e.Err)
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Timeout reports whether the operation failed because a timeout expired
+func (e *Error) Timeout() bool {
+	var ne net.Error
+	return errors.As(e.Err, &ne) && ne.Timeout()
+}
+
+// Printer is a ZPL printer reachable via a raw TCP port or another
+// Transport. The zero timeouts disable the respective timeout, New sets
+// defaults for all of them.
+type Printer struct {
+	// Addr is the host and port of the printer, e.g. "192.168.178.42:9100"
+	// or "[fd00::42]:9100", or the name of the printer for other transports
+	Addr string
+	// Transport connects to the printer, nil connects to the raw TCP port at Addr
+	Transport Transport
+	// TLSConfig enables TLS for the raw TCP port, as offered by Link-OS
+	// printers on TLSPort
+	TLSConfig *tls.Config
+	// DialTimeout limits the time to connect to the printer
+	DialTimeout time.Duration
+	// WriteTimeout limits the time to send a job
+	WriteTimeout time.Duration
+	// ReadTimeout limits the time to wait for the first byte of a response
+	ReadTimeout time.Duration
+	// IdleTimeout ends unframed responses (like ~HD) if the printer hasn't
+	// sent anything for this duration
+	IdleTimeout time.Duration
+}
+
+// New returns a Printer for addr (host:port) with the default timeouts
+func New(addr string) *Printer {
+	return &Printer{
+		Addr:         addr,
+		DialTimeout:  DefaultDialTimeout,
+		WriteTimeout: DefaultWriteTimeout,
+		ReadTimeout:  DefaultReadTimeout,
+		IdleTimeout:  DefaultIdleTimeout,
+	}
+}
+
+func (p *Printer) err(op string, err error) error {
+	return &Error{Op: op, Addr: p.Addr, Err: err}
+}
+
+// deadline returns the earlier point in time of now+timeout and the deadline of ctx
+func deadline(ctx context.Context, timeout time.Duration) time.Time {
+	var t time.Time
+	if timeout > 0 {
+		t = time.Now().Add(timeout)
+	}
+	if d, ok := ctx.Deadline(); ok && (t.IsZero() || d.Before(t)) {
+		t = d
+	}
+	return t
+}
+
+// conn is a connection to the printer, which is interrupted when the
+// context it was opened with is done
+type conn struct {
+	Conn
+	p      *Printer
+	ctx    context.Context
+	reader *bufio.Reader
+	done   chan struct{}
+}
+
+// open connects to the printer via its transport
+func (p *Printer) open(ctx context.Context) (Conn, error) {
+	if p.Transport == nil {
+		dialer := &net.Dialer{Timeout: p.DialTimeout}
+		if p.TLSConfig != nil {
+			tlsDialer := tls.Dialer{NetDialer: dialer, Config: p.TLSConfig}
+			return tlsDialer.DialContext(ctx, "tcp", p.Addr)
+		}
+		return dialer.DialContext(ctx, "tcp", p.Addr)
+	}
+	if p.DialTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, p.DialTimeout)
+		defer cancel()
+	}
+	return p.Transport.Open(ctx)
+}
+
+func (p *Printer) dial(ctx context.Context) (*conn, error) {
+	c, err := p.open(ctx)
+	if err != nil {
+		return nil, p.err("dial", err)
+	}
+	cn := &conn{Conn: c, p: p, ctx: ctx, reader: bufio.NewReader(c), done: make(chan struct{})}
+	go func() {
+		select {
+		case <-ctx.Done():
+			// unblock pending reads and writes
+			c.SetDeadline(time.Unix(1, 0))
+		case <-cn.done:
+		}
+	}()
+	return cn, nil
+}
+
+// Close closes the connection, job based transports submit their job with
+// the context of the connection
+func (c *conn) Close() error {
+	defer close(c.done)
+	if jc, ok := c.Conn.(*jobConn); ok {
+		return jc.closeContext(c.ctx)
+	}
+	return c.Conn.Close()
+}
+
+// opErr wraps err of the operation op, preferring the error of the context
+func (c *conn) opErr(op string, err error) error {
+	if ctxErr := c.ctx.Err(); ctxErr != nil {
+		err = ctxErr
+	}
+	return c.p.err(op, err)
+}
+
+// setDeadline applies a timeout via set. The context is checked afterwards,
+// as a cancellation right before would have been overwritten.
+func (c *conn) setDeadline(op string, set func(time.Time) error, timeout time.Duration) error {
+	set(deadline(c.ctx, timeout))
+	if err := c.ctx.Err(); err != nil {
+		return c.p.err(op, err)
+	}
+	return nil
+}
+
+// send writes a command followed by the line breaks the printers expect
+func (c *conn) send(cmd string) error {
+	if err := c.setDeadline("write", c.SetWriteDeadline, c.p.WriteTimeout); err != nil {
+		return err
+	}
+	if _, err := io.WriteString(c, cmd+"\r\n\r\n"); err != nil {
+		return c.opErr("write", err)
+	}
+	return nil
+}
+
+// readLine reads a single line of a response
+func (c *conn) readLine() (string, error) {
+	if err := c.setDeadline("read", c.SetReadDeadline, c.p.ReadTimeout); err != nil {
+		return "", err
+	}
+	line, err := c.reader.ReadString('\n')
+	if err != nil {
+		return "", c.opErr("read", err)
+	}
+	return line, nil
+}
+
+// readLines reads n lines of a response
+func (c *conn) readLines(n int) (string, error) {
+	var sb strings.Builder
+	for i := 0; i < n; i++ {
+		line, err := c.readLine()
+		if err != nil {
+			return "", err
+		}
+		sb.WriteString(line)
+	}
+	return sb.String(), nil
+}
+
+// readFramed reads a response framed by STX and ETX and returns the text in
+// between. The read timeout applies to every chunk of the response, so slow
+// printers aren't cut off as long as they keep sending.
+func (c *conn) readFramed() (string, error) {
+	var sb strings.Builder
+	started := false
+	for {
+		if c.reader.Buffered() == 0 {
+			if err := c.setDeadline("read", c.SetReadDeadline, c.p.ReadTimeout); err != nil {
+				return "", err
+			}
+		}
+		b, err := c.reader.ReadByte()
+		if err != nil {
+			return "", c.opErr("read", err)
+		}
+		switch {
+		case !started:
+			started = b == stx
+		case b == etx:
+			return sb.String(), nil
+		default:
+			sb.WriteByte(b)
+		}
+	}
+}
+
+// readUntilIdle reads a response until the printer stops sending for IdleTimeout
+func (c *conn) readUntilIdle() (string, error) {
+	var sb strings.Builder
+	if err := c.setDeadline("read", c.SetReadDeadline, c.p.ReadTimeout); err != nil {
+		return "", err
+	}
+	for {
+		line, err := c.reader.ReadString('\n')
+		sb.WriteString(line)
+		if err != nil {
+			var ne net.Error
+			if sb.Len() > 0 && c.ctx.Err() == nil && errors.As(err, &ne) && ne.Timeout() {
+				return sb.String(), nil
+			}
+			if sb.Len() > 0 && err == io.EOF {
+				return sb.String(), nil
+			}
+			return "", c.opErr("read", err)
+		}
+		if err := c.setDeadline("read", c.SetReadDeadline, c.p.IdleTimeout); err != nil {
+			return "", err
+		}
+	}
+}
+
+// Send sends the ZPL data to the printer
+func (p *Printer) Send(ctx context.Context, zpl string) error {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return err
+	}
+	err = c.send(zpl)
+	// job based transports submit the data when closed
+	if cerr := c.Close(); err == nil && cerr != nil {
+		err = c.opErr("write", cerr)
+	}
+	return err
+}
+
+// Feed prints an empty label
+func (p *Printer) Feed(ctx context.Context) error {
+	return p.Send(ctx, "^xa^aa^fd ^fs^xz")
+}
+
+// Calibrate calibrates the media and ribbon sensors (~JC) and saves the settings (^JUS)
+func (p *Printer) Calibrate(ctx context.Context) error {
+	return p.Send(ctx, "~jc^xa^jus^xz")
+}
+
+// Cancel cancels all formats in the buffer of the printer (~JA)
+func (p *Printer) Cancel(ctx context.Context) error {
+	return p.Send(ctx, "~ja")
+}
+
+// Info returns the raw responses of the printer to ~HI (Host Identification)
+// and ~HS (Host Status)
+func (p *Printer) Info(ctx context.Context) (string, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	if err := c.send("~HI"); err != nil {
+		return "", err
+	}
+	info, err := c.readLine()
+	if err != nil {
+		return "", err
+	}
+	if err := c.send("~HS"); err != nil {
+		return "", err
+	}
+	status, err := c.readLines(3)
+	if err != nil {
+		return "", err
+	}
+	return info + status, nil
+}
+
+// terminalOutput sends cmd and returns the text the printer responds with
+func (p *Printer) terminalOutput(ctx context.Context, cmd string) (string, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	if err := c.send(cmd); err != nil {
+		return "", err
+	}
+	return c.readUntilIdle()
+}
+
+// Config returns the configuration label of the printer as text (^HH)
+func (p *Printer) Config(ctx context.Context) (string, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer c.Close()
+
+	if err := c.send("^XA^HH^XZ"); err != nil {
+		return "", err
+	}
+	return c.readFramed()
+}
+
+// Diagnostics returns the head diagnostic report of the printer (~HD)
+func (p *Printer) Diagnostics(ctx context.Context) (string, error) {
+	return p.terminalOutput(ctx, "~HD")
+}
@@ -0,0 +1,153 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// serve starts a TCP server on localhost, which handles every connection
+// with handle, and returns a Printer connected to it
+func serve(t *testing.T, handle func(c net.Conn)) *Printer {
+	t.Helper()
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+	p := New(l.Addr().String())
+	p.ReadTimeout = time.Second
+	p.IdleTimeout = 50 * time.Millisecond
+	return p
+}
+
+// respond answers every received command line with the response of answers
+func respond(answers map[string]string) func(c net.Conn) {
+	return func(c net.Conn) {
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			if answer, ok := answers[scanner.Text()]; ok {
+				io.WriteString(c, answer)
+			}
+		}
+	}
+}
+
+func TestSend(t *testing.T) {
+	received := make(chan string, 1)
+	p := serve(t, func(c net.Conn) {
+		data, _ := io.ReadAll(c)
+		received <- string(data)
+	})
+	if err := p.Send(context.Background(), "^XA^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got != "^XA^XZ\r\n\r\n" {
+		t.Fatalf("printer received %q", got)
+	}
+}
+
+func TestCommands(t *testing.T) {
+	received := make(chan string, 3)
+	p := serve(t, func(c net.Conn) {
+		data, _ := io.ReadAll(c)
+		received <- strings.TrimSpace(string(data))
+	})
+	ctx := context.Background()
+	for cmd, f := range map[string]func(context.Context) error{
+		"~ja":              p.Cancel,
+		"~jc^xa^jus^xz":    p.Calibrate,
+		"^xa^aa^fd ^fs^xz": p.Feed,
+	} {
+		if err := f(ctx); err != nil {
+			t.Fatal(err)
+		}
+		if got := <-received; got != cmd {
+			t.Fatalf("expected %q, printer received %q", cmd, got)
+		}
+	}
+}
+
+func TestInfo(t *testing.T) {
+	p := serve(t, respond(map[string]string{
+		"~HI": "\x02ZT410-203dpi,V75.20.01Z,8,8192KB\x03\r\n",
+		"~HS": "\x02030,0,0,1245,000,0,0,0,000,0,0,0\x03\r\n\x02000,0,0,0,0,2,4,0,00000000,1,000\x03\r\n\x021234,0\x03\r\n",
+	}))
+	info, err := p.Info(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(info, "\x02ZT410") || strings.Count(info, "\n") != 4 {
+		t.Fatalf("unexpected info %q", info)
+	}
+}
+
+func TestConfig(t *testing.T) {
+	p := serve(t, func(c net.Conn) {
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			switch scanner.Text() {
+			case "^XA^HH^XZ":
+				// pause longer than the idle timeout within the framed response
+				io.WriteString(c, "\x02  +10.0               DARKNESS\r\n")
+				time.Sleep(200 * time.Millisecond)
+				io.WriteString(c, "  4 IPS               PRINT SPEED\r\n\x03")
+			case "~HD":
+				io.WriteString(c, "Head Temp = 25\r\n")
+			}
+		}
+	})
+	config, err := p.Config(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(config, "DARKNESS") || !strings.Contains(config, "PRINT SPEED") {
+		t.Fatalf("unexpected config %q", config)
+	}
+	diag, err := p.Diagnostics(context.Background())
+	if err != nil || diag != "Head Temp = 25\r\n" {
+		t.Fatalf("unexpected diagnostics %q, %v", diag, err)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	p := serve(t, respond(nil))
+	p.ReadTimeout = 50 * time.Millisecond
+
+	_, err := p.Info(context.Background())
+	var perr *Error
+	if !errors.As(err, &perr) || perr.Op != "read" || !perr.Timeout() {
+		t.Fatalf("expected a read timeout, got %v", err)
+	}
+
+	p.ReadTimeout = time.Minute
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+	if _, err := p.Config(ctx); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the canceled context as error, got %v", err)
+	}
+
+	l, _ := net.Listen("tcp4", "127.0.0.1:0")
+	l.Close()
+	err = New(l.Addr().String()).Send(context.Background(), "^XA^XZ")
+	if !errors.As(err, &perr) || perr.Op != "dial" {
+		t.Fatalf("expected a dial error, got %v", err)
+	}
+}
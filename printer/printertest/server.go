@@ -0,0 +1,466 @@
+// Package printertest provides a fake printer for tests of code printing
+// via the raw TCP port. It speaks the subset of ZPL and SGD the printer
+// package uses, records the received jobs and injects faults.
+package printertest
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Fault is an error the server simulates when it receives a command
+type Fault int
+
+// Faults
+const (
+	// NoFault answers every command
+	NoFault Fault = iota
+	// PartialResponse sends the first half of a response and closes the connection
+	PartialResponse
+	// DropConnection closes the connection without handling the command
+	DropConnection
+)
+
+// Identity is the ~HI response of the server if not changed
+const Identity = "ZT410-203dpi,V75.20.01Z,8,8192KB"
+
+// DefaultPrintDelay is the time a format stays in the buffer of the server
+// before it is printed, unless changed by SetPrintDelay
+const DefaultPrintDelay = 100 * time.Millisecond
+
+// odometerVar is the SGD setting counting the printed labels
+const odometerVar = "odometer.total_label_count"
+
+// Server is a fake printer listening on a local TCP port
+type Server struct {
+	// Addr is the host and port of the server, e.g. "127.0.0.1:41235"
+	Addr string
+
+	listener net.Listener
+	wg       sync.WaitGroup
+
+	mu       sync.Mutex
+	conns    map[net.Conn]bool
+	identity string
+	paperOut bool
+	headOpen bool
+	paused   bool
+	delay    time.Duration
+	fault    Fault
+	// faults is the number of commands fault applies to, -1 for all
+	faults     int
+	printDelay time.Duration
+	// buffer holds the receive times of the formats not printed yet
+	buffer   []time.Time
+	printed  int
+	vars     map[string]string
+	jobs     []string
+	commands []string
+	// received is closed and replaced whenever a job or command is received
+	received chan struct{}
+}
+
+// NewServer starts a fake printer on a random local port, it has to be
+// closed by Close
+func NewServer() *Server {
+	l, err := net.Listen("tcp4", "127.0.0.1:0")
+	if err != nil {
+		panic(fmt.Sprintf("printertest: failed to listen: %v", err))
+	}
+	s := &Server{
+		Addr:       l.Addr().String(),
+		listener:   l,
+		conns:      map[net.Conn]bool{},
+		received:   make(chan struct{}),
+		identity:   Identity,
+		faults:     -1,
+		printDelay: DefaultPrintDelay,
+		vars: map[string]string{
+			"device.friendly_name": "printertest",
+			"device.unique_id":     "PT0000001",
+			"print.tone":           "10.0",
+			"media.speed":          "4.0",
+			"ezpl.print_width":     "832",
+			"ezpl.media_type":      "GAP/NOTCH",
+			odometerVar:            "0",
+		},
+	}
+	s.wg.Add(1)
+	go s.serve()
+	return s
+}
+
+// Close stops the server and closes all open connections
+func (s *Server) Close() {
+	s.listener.Close()
+	s.mu.Lock()
+	for c := range s.conns {
+		c.Close()
+	}
+	s.mu.Unlock()
+	s.wg.Wait()
+}
+
+// Jobs returns the received formats (^XA…^XZ) in order
+func (s *Server) Jobs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.jobs...)
+}
+
+// Commands returns the received control (~) and SGD commands in order
+func (s *Server) Commands() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]string(nil), s.commands...)
+}
+
+// wait waits until n returns at least count, the connections of the
+// printer are independent, so a job sent before a query might be received
+// after it.
+func (s *Server) wait(ctx context.Context, count int, n func() []string) ([]string, error) {
+	for {
+		s.mu.Lock()
+		received, got := s.received, n()
+		s.mu.Unlock()
+		if len(got) >= count {
+			return append([]string(nil), got...), nil
+		}
+		select {
+		case <-received:
+		case <-ctx.Done():
+			return got, ctx.Err()
+		}
+	}
+}
+
+// WaitJobs waits until at least n jobs were received and returns them
+func (s *Server) WaitJobs(ctx context.Context, n int) ([]string, error) {
+	return s.wait(ctx, n, func() []string { return s.jobs })
+}
+
+// WaitCommands waits until at least n commands were received and returns them
+func (s *Server) WaitCommands(ctx context.Context, n int) ([]string, error) {
+	return s.wait(ctx, n, func() []string { return s.commands })
+}
+
+// SetPaperOut sets the paper out flag of the status responses
+func (s *Server) SetPaperOut(paperOut bool) {
+	s.mu.Lock()
+	s.paperOut = paperOut
+	s.mu.Unlock()
+}
+
+// SetHeadOpen sets the head open flag of the status responses
+func (s *Server) SetHeadOpen(headOpen bool) {
+	s.mu.Lock()
+	s.headOpen = headOpen
+	s.mu.Unlock()
+}
+
+// SetPaused sets the paused flag of the status responses
+func (s *Server) SetPaused(paused bool) {
+	s.mu.Lock()
+	s.paused = paused
+	s.mu.Unlock()
+}
+
+// SetIdentity changes the ~HI response, e.g. "ZD420-300dpi,V84.20.18Z,12,8192KB"
+func (s *Server) SetIdentity(identity string) {
+	s.mu.Lock()
+	s.identity = identity
+	s.mu.Unlock()
+}
+
+// SetDelay delays every response by d
+func (s *Server) SetDelay(d time.Duration) {
+	s.mu.Lock()
+	s.delay = d
+	s.mu.Unlock()
+}
+
+// SetFault injects f into the handling of all following commands
+func (s *Server) SetFault(f Fault) {
+	s.InjectFault(f, -1)
+}
+
+// InjectFault injects f into the handling of the next n commands, formats
+// included
+func (s *Server) InjectFault(f Fault, n int) {
+	s.mu.Lock()
+	s.fault, s.faults = f, n
+	s.mu.Unlock()
+}
+
+// SetPrintDelay changes the time a format stays in the buffer before it is
+// printed. Formats aren't printed while the printer is paused, out of paper
+// or its head is open.
+func (s *Server) SetPrintDelay(d time.Duration) {
+	s.mu.Lock()
+	s.printDelay = d
+	s.mu.Unlock()
+}
+
+// Printed returns the number of printed formats
+func (s *Server) Printed() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.print()
+	return s.printed
+}
+
+// print removes the formats from the buffer which are printed by now and
+// counts them in odometer.total_label_count
+func (s *Server) print() {
+	if s.paused || s.paperOut || s.headOpen {
+		return
+	}
+	for len(s.buffer) > 0 && time.Since(s.buffer[0]) >= s.printDelay {
+		s.buffer = s.buffer[1:]
+		s.printed++
+		if n, err := strconv.Atoi(s.vars[odometerVar]); err == nil {
+			s.vars[odometerVar] = strconv.Itoa(n + 1)
+		}
+	}
+}
+
+// Var returns the value of an SGD setting
+func (s *Server) Var(name string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vars[name]
+}
+
+// SetVar changes an SGD setting
+func (s *Server) SetVar(name, value string) {
+	s.mu.Lock()
+	s.vars[name] = value
+	s.mu.Unlock()
+}
+
+func (s *Server) serve() {
+	defer s.wg.Done()
+	for {
+		c, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.conns[c] = true
+		s.mu.Unlock()
+		s.wg.Add(1)
+		go func() {
+			defer s.wg.Done()
+			s.handle(c)
+			s.mu.Lock()
+			delete(s.conns, c)
+			s.mu.Unlock()
+			c.Close()
+		}()
+	}
+}
+
+// handle reads the commands of a connection until it is closed
+func (s *Server) handle(c net.Conn) {
+	r := bufio.NewReader(c)
+	for {
+		cmd, err := readCommand(r)
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		fault, delay := s.fault, s.delay
+		if s.faults > 0 {
+			s.faults--
+			if s.faults == 0 {
+				s.fault = NoFault
+			}
+		}
+		s.mu.Unlock()
+		if fault == DropConnection {
+			return
+		}
+
+		response := s.execute(cmd)
+		if response == "" {
+			continue
+		}
+		time.Sleep(delay)
+		if fault == PartialResponse {
+			io.WriteString(c, response[:len(response)/2])
+			return
+		}
+		if _, err := io.WriteString(c, response); err != nil {
+			return
+		}
+	}
+}
+
+// readCommand reads the next command: a control command like ~HS, a format
+// from ^XA to ^XZ or an SGD command line
+func readCommand(r *bufio.Reader) (string, error) {
+	for {
+		b, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		switch b {
+		case '~':
+			cmd := []byte{b}
+			for len(cmd) < 3 || len(cmd) < 5 && bytes.EqualFold(cmd[:3], []byte("~HQ")) {
+				b, err := r.ReadByte()
+				if err != nil {
+					return "", err
+				}
+				cmd = append(cmd, b)
+			}
+			return strings.ToUpper(string(cmd)), nil
+		case '^':
+			format := []byte{b}
+			for len(format) < 3 || !bytes.EqualFold(format[len(format)-3:], []byte("^XZ")) {
+				b, err := r.ReadByte()
+				if err != nil {
+					return "", err
+				}
+				format = append(format, b)
+			}
+			return string(format), nil
+		case '!', '{':
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimSpace(string(b) + line), nil
+		}
+	}
+}
+
+// execute handles a command and returns the response
+func (s *Server) execute(cmd string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	close(s.received)
+	s.received = make(chan struct{})
+
+	if strings.HasPrefix(cmd, "^") {
+		if strings.EqualFold(cmd, "^XA^HH^XZ") {
+			return s.configuration()
+		}
+		s.jobs = append(s.jobs, cmd)
+		s.buffer = append(s.buffer, time.Now())
+		return ""
+	}
+	s.commands = append(s.commands, cmd)
+
+	switch {
+	case cmd == "~HS":
+		return s.hostStatus()
+	case strings.EqualFold(cmd, "~JA"):
+		s.buffer = nil
+	case cmd == "~HI":
+		return "\x02" + s.identity + "\x03\r\n"
+	case cmd == "~HQES":
+		return s.errorStatus()
+	case cmd == "~HD":
+		return "Head Temp = 25\r\nAmbient Temp = 22\r\nHead Test = Passed\r\n"
+	case cmd == `{}{"allconfig":null}`:
+		return s.allConfig()
+	case strings.HasPrefix(cmd, "! U1 "):
+		return s.sgd(cmd)
+	}
+	return ""
+}
+
+func flag(set bool) int {
+	if set {
+		return 1
+	}
+	return 0
+}
+
+func (s *Server) hostStatus() string {
+	s.print()
+	return fmt.Sprintf("\x02030,%d,%d,1245,%03d,0,0,0,000,0,0,0\x03\r\n", flag(s.paperOut), flag(s.paused), len(s.buffer)) +
+		fmt.Sprintf("\x02000,0,%d,0,0,2,4,0,00000000,1,000\x03\r\n", flag(s.headOpen)) +
+		"\x021234,0\x03\r\n"
+}
+
+func (s *Server) errorStatus() string {
+	var errors uint32
+	if s.paperOut {
+		errors |= 1 << 0
+	}
+	if s.headOpen {
+		errors |= 1 << 2
+	}
+	if s.paused {
+		errors |= 1 << 17
+	}
+	return fmt.Sprintf("\x02\r\n  PRINTER STATUS\r\n   ERRORS:         %d 00000000 %08X\r\n   WARNINGS:       0 00000000 00000000\r\n\x03\r\n",
+		flag(errors != 0), errors)
+}
+
+func (s *Server) configuration() string {
+	var sb strings.Builder
+	sb.WriteString("\x02  PRINTER CONFIGURATION\r\n\r\n")
+	for _, setting := range []struct{ value, name string }{
+		{"+" + s.vars["print.tone"], "DARKNESS"},
+		{s.vars["media.speed"] + " IPS", "PRINT SPEED"},
+		{"TEAR OFF", "PRINT MODE"},
+		{s.vars["ezpl.media_type"], "MEDIA TYPE"},
+		{"THERMAL-TRANS.", "PRINT METHOD"},
+		{s.vars["ezpl.print_width"] + " 8/MM FULL", "PRINT WIDTH"},
+		{"1245", "LABEL LENGTH"},
+	} {
+		fmt.Fprintf(&sb, "  %-20s%s\r\n", setting.value, setting.name)
+	}
+	sb.WriteString("FIRMWARE IN THIS PRINTER IS COPYRIGHTED\r\n\x03")
+	return sb.String()
+}
+
+func (s *Server) allConfig() string {
+	type setting struct {
+		Value  string `json:"value"`
+		Type   string `json:"type"`
+		Access string `json:"access"`
+	}
+	config := map[string]setting{}
+	s.print()
+	for name, value := range s.vars {
+		config[name] = setting{Value: value, Type: "string", Access: "RW"}
+	}
+	response, _ := json.Marshal(map[string]interface{}{"allconfig": config})
+	return string(response) + "\r\n"
+}
+
+// sgd handles the SGD command line cmd like `! U1 getvar "print.tone"`
+func (s *Server) sgd(cmd string) string {
+	args := strings.Split(strings.TrimPrefix(cmd, "! U1 "), `"`)
+	if len(args) < 2 {
+		return ""
+	}
+	name := args[1]
+	s.print()
+	switch strings.TrimSpace(args[0]) {
+	case "getvar":
+		value, ok := s.vars[name]
+		if !ok {
+			value = "?"
+		}
+		return `"` + value + `"`
+	case "setvar":
+		if len(args) > 3 {
+			s.vars[name] = args[3]
+		}
+	}
+	return ""
+}
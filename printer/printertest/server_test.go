@@ -0,0 +1,169 @@
+package printertest_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/PaackEng/zplgfa/printer"
+	"github.com/PaackEng/zplgfa/printer/printertest"
+)
+
+func newPrinter(t *testing.T) (*printertest.Server, *printer.Printer) {
+	t.Helper()
+	s := printertest.NewServer()
+	t.Cleanup(s.Close)
+	p := printer.New(s.Addr)
+	p.ReadTimeout = time.Second
+	p.IdleTimeout = 50 * time.Millisecond
+	return s, p
+}
+
+func TestJobs(t *testing.T) {
+	s, p := newPrinter(t)
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := p.Send(ctx, "^XA^FO0,0^GB10,10,10^FS^XZ\n^XA^FDsecond^FS^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	// both labels come on one connection, the commands below use new ones,
+	// which the server may read first
+	if _, err := s.WaitJobs(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Calibrate(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Cancel(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{"^XA^FO0,0^GB10,10,10^FS^XZ", "^XA^FDsecond^FS^XZ", "^xa^jus^xz"}
+	if jobs, err := s.WaitJobs(ctx, 3); err != nil || !reflect.DeepEqual(jobs, want) {
+		t.Fatalf("Jobs = %q, %v, want %q", jobs, err, want)
+	}
+	commands, err := s.WaitCommands(ctx, 2)
+	if err != nil || len(commands) != 2 {
+		t.Fatalf("unexpected commands %q, %v", commands, err)
+	}
+	short, cancelShort := context.WithTimeout(ctx, 50*time.Millisecond)
+	defer cancelShort()
+	if _, err := s.WaitJobs(short, 4); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the wait for a missing job to time out, got %v", err)
+	}
+}
+
+func TestQueries(t *testing.T) {
+	s, p := newPrinter(t)
+	ctx := context.Background()
+
+	id, err := p.Identify(ctx)
+	if err != nil || id.Model != "ZT410-203dpi" || id.DPI() != 203 {
+		t.Fatalf("Identify = %+v, %v", id, err)
+	}
+	config, err := p.Configuration(ctx)
+	if err != nil || config.Darkness != 10 || config.PrintWidth != 832 || config.MediaType != "GAP/NOTCH" {
+		t.Fatalf("Configuration = %+v, %v", config, err)
+	}
+	if diag, err := p.Diagnostics(ctx); err != nil || diag == "" {
+		t.Fatalf("Diagnostics = %q, %v", diag, err)
+	}
+
+	if err := p.SetVar(ctx, "print.tone", "15.0"); err != nil {
+		t.Fatal(err)
+	}
+	if tone, err := p.GetVar(ctx, "print.tone"); err != nil || tone != "15.0" {
+		t.Fatalf("GetVar = %q, %v", tone, err)
+	}
+	if s.Var("print.tone") != "15.0" {
+		t.Fatalf("setting wasn't changed: %q", s.Var("print.tone"))
+	}
+	all, err := p.AllConfig(ctx)
+	if err != nil || all["device.friendly_name"].Value != "printertest" {
+		t.Fatalf("AllConfig = %v, %v", all, err)
+	}
+}
+
+func TestStatusFaults(t *testing.T) {
+	s, p := newPrinter(t)
+	ctx := context.Background()
+
+	status, err := p.Status(ctx)
+	if err != nil || !status.Ready() {
+		t.Fatalf("expected a ready printer, got %+v, %v", status, err)
+	}
+	s.SetPaperOut(true)
+	s.SetHeadOpen(true)
+	status, err = p.Status(ctx)
+	if err != nil || !status.PaperOut || !status.HeadOpen {
+		t.Fatalf("expected paper out and head open, got %+v, %v", status, err)
+	}
+	errs, err := p.Errors(ctx)
+	if err != nil || errs.Errors != printer.MediaOut|printer.HeadOpen {
+		t.Fatalf("Errors = %+v, %v", errs, err)
+	}
+}
+
+func TestConnectionFaults(t *testing.T) {
+	s, p := newPrinter(t)
+	ctx := context.Background()
+	var perr *printer.Error
+
+	s.SetDelay(100 * time.Millisecond)
+	p.ReadTimeout = 20 * time.Millisecond
+	if _, err := p.Status(ctx); !errors.As(err, &perr) || !perr.Timeout() {
+		t.Fatalf("expected a timeout for a slow response, got %v", err)
+	}
+	p.ReadTimeout = time.Second
+	if _, err := p.Status(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.SetDelay(0)
+	s.SetFault(printertest.PartialResponse)
+	if _, err := p.Status(ctx); !errors.As(err, &perr) || perr.Op != "read" {
+		t.Fatalf("expected a read error for a partial response, got %v", err)
+	}
+
+	s.SetFault(printertest.DropConnection)
+	if _, err := p.Identify(ctx); !errors.As(err, &perr) || perr.Op != "read" {
+		t.Fatalf("expected a read error for a dropped connection, got %v", err)
+	}
+	s.SetFault(printertest.NoFault)
+	if _, err := p.Identify(ctx); err != nil {
+		t.Fatal(err)
+	}
+
+	s.InjectFault(printertest.DropConnection, 1)
+	if _, err := p.Identify(ctx); err == nil {
+		t.Fatal("expected the first command to fail")
+	}
+	if _, err := p.Identify(ctx); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestPrinting(t *testing.T) {
+	s, p := newPrinter(t)
+	ctx := context.Background()
+	s.SetPaused(true)
+	s.SetPrintDelay(10 * time.Millisecond)
+	if err := p.Send(ctx, "^XA^XZ^XA^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.WaitJobs(ctx, 2); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(20 * time.Millisecond)
+	if status, err := p.Status(ctx); err != nil || status.FormatsInBuffer != 2 || s.Printed() != 0 {
+		t.Fatalf("expected 2 formats in the buffer of the paused printer, got %+v, %v", status, err)
+	}
+
+	s.SetPaused(false)
+	time.Sleep(20 * time.Millisecond)
+	if status, err := p.Status(ctx); err != nil || status.FormatsInBuffer != 0 || s.Printed() != 2 {
+		t.Fatalf("expected 2 printed formats, got %+v, %v", status, err)
+	}
+}
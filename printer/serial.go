@@ -0,0 +1,86 @@
+package printer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Parity of a serial port
+type Parity int
+
+// Parities
+const (
+	ParityNone Parity = iota
+	ParityEven
+	ParityOdd
+)
+
+// FlowControl of a serial port
+type FlowControl int
+
+// Flow controls
+const (
+	FlowNone FlowControl = iota
+	// FlowHardware uses the RTS and CTS lines
+	FlowHardware
+	// FlowSoftware uses XON and XOFF characters
+	FlowSoftware
+)
+
+// ParseParity parses "none", "even" or "odd"
+func ParseParity(s string) (Parity, error) {
+	switch strings.ToLower(s) {
+	case "", "none", "n":
+		return ParityNone, nil
+	case "even", "e":
+		return ParityEven, nil
+	case "odd", "o":
+		return ParityOdd, nil
+	}
+	return 0, fmt.Errorf("unknown parity %q", s)
+}
+
+// ParseFlowControl parses "none", "hardware" (or "rtscts") and "software"
+// (or "xonxoff")
+func ParseFlowControl(s string) (FlowControl, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return FlowNone, nil
+	case "hardware", "rtscts":
+		return FlowHardware, nil
+	case "software", "xonxoff":
+		return FlowSoftware, nil
+	}
+	return 0, fmt.Errorf("unknown flow control %q", s)
+}
+
+// Serial is a Transport to a printer attached to a serial port. The port is
+// used with 8 data bits and 1 stop bit, the printer defaults.
+type Serial struct {
+	Path        string
+	BaudRate    int
+	Parity      Parity
+	FlowControl FlowControl
+}
+
+// DefaultBaudRate is the baud rate of serial ports if not set
+const DefaultBaudRate = 9600
+
+// Open opens and configures the serial port
+func (s Serial) Open(ctx context.Context) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if s.BaudRate == 0 {
+		s.BaudRate = DefaultBaudRate
+	}
+	return openSerial(s)
+}
+
+// NewSerial returns a Printer for the serial port with the default timeouts
+func NewSerial(serial Serial) *Printer {
+	p := New(serial.Path)
+	p.Transport = serial
+	return p
+}
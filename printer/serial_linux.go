@@ -0,0 +1,81 @@
+package printer
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+var baudRates = map[int]uint32{
+	1200:   unix.B1200,
+	2400:   unix.B2400,
+	4800:   unix.B4800,
+	9600:   unix.B9600,
+	19200:  unix.B19200,
+	38400:  unix.B38400,
+	57600:  unix.B57600,
+	115200: unix.B115200,
+	230400: unix.B230400,
+}
+
+// openSerial opens the serial port in raw mode with the settings of s
+func openSerial(s Serial) (Conn, error) {
+	speed, ok := baudRates[s.BaudRate]
+	if !ok {
+		return nil, fmt.Errorf("unsupported baud rate %d", s.BaudRate)
+	}
+
+	f, err := os.OpenFile(s.Path, os.O_RDWR|unix.O_NOCTTY|unix.O_NONBLOCK, 0)
+	if err != nil {
+		return nil, err
+	}
+	// Fd would switch the file to blocking mode, which disables deadlines
+	rc, err := f.SyscallConn()
+	if err == nil {
+		cerr := rc.Control(func(fd uintptr) { err = configureSerial(int(fd), s, speed) })
+		if cerr != nil {
+			err = cerr
+		}
+	}
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	return f, nil
+}
+
+// configureSerial sets the serial port fd to raw mode with the settings of s
+func configureSerial(fd int, s Serial, speed uint32) error {
+	t, err := unix.IoctlGetTermios(fd, unix.TCGETS)
+	if err != nil {
+		return fmt.Errorf("%s isn't a serial port: %w", s.Path, err)
+	}
+
+	// raw mode, see cfmakeraw(3)
+	t.Iflag &^= unix.IGNBRK | unix.BRKINT | unix.PARMRK | unix.ISTRIP | unix.INLCR | unix.IGNCR | unix.ICRNL | unix.IXON | unix.IXOFF
+	t.Oflag &^= unix.OPOST
+	t.Lflag &^= unix.ECHO | unix.ECHONL | unix.ICANON | unix.ISIG | unix.IEXTEN
+	t.Cflag &^= unix.CSIZE | unix.PARENB | unix.PARODD | unix.CSTOPB | unix.CRTSCTS | unix.CBAUD
+	t.Cflag |= unix.CS8 | unix.CREAD | unix.CLOCAL | speed
+	t.Ispeed, t.Ospeed = speed, speed
+	t.Cc[unix.VMIN], t.Cc[unix.VTIME] = 1, 0
+
+	switch s.Parity {
+	case ParityEven:
+		t.Cflag |= unix.PARENB
+	case ParityOdd:
+		t.Cflag |= unix.PARENB | unix.PARODD
+	}
+	switch s.FlowControl {
+	case FlowHardware:
+		t.Cflag |= unix.CRTSCTS
+	case FlowSoftware:
+		t.Iflag |= unix.IXON | unix.IXOFF
+	}
+
+	if err := unix.IoctlSetTermios(fd, unix.TCSETS, t); err != nil {
+		return fmt.Errorf("failed to configure %s: %w", s.Path, err)
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/sys/unix"
+)
+
+// openPTY opens a pseudo-terminal and returns its master and the path of its slave
+func openPTY(t *testing.T) (*os.File, string) {
+	t.Helper()
+	master, err := os.OpenFile("/dev/ptmx", os.O_RDWR|unix.O_NOCTTY, 0)
+	if err != nil {
+		t.Skipf("no pseudo-terminals: %v", err)
+	}
+	t.Cleanup(func() { master.Close() })
+	rc, err := master.SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var n int
+	rc.Control(func(fd uintptr) {
+		if err = unix.IoctlSetPointerInt(int(fd), unix.TIOCSPTLCK, 0); err == nil {
+			n, err = unix.IoctlGetInt(int(fd), unix.TIOCGPTN)
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return master, "/dev/pts/" + strconv.Itoa(n)
+}
+
+// respondPTY answers every command line the master receives with the
+// response of answers
+func respondPTY(master *os.File, answers map[string]string) {
+	go func() {
+		scanner := bufio.NewScanner(master)
+		for scanner.Scan() {
+			if answer, ok := answers[strings.TrimSpace(scanner.Text())]; ok {
+				io.WriteString(master, answer)
+			}
+		}
+	}()
+}
+
+func TestSerial(t *testing.T) {
+	master, path := openPTY(t)
+	respondPTY(master, map[string]string{"~HS": hostStatus})
+
+	p := NewSerial(Serial{Path: path, BaudRate: 115200, Parity: ParityEven, FlowControl: FlowSoftware})
+	p.ReadTimeout = time.Second
+	status, err := p.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.PaperOut || status.LabelLength != 1245 {
+		t.Fatalf("unexpected status %+v", status)
+	}
+
+	// unanswered queries time out, the serial port supports deadlines
+	p.ReadTimeout = 50 * time.Millisecond
+	var perr *Error
+	if _, err := p.Identify(context.Background()); !errors.As(err, &perr) || !perr.Timeout() {
+		t.Fatalf("expected a read timeout, got %v", err)
+	}
+
+	if _, err := NewSerial(Serial{Path: path, BaudRate: 1234}).Status(context.Background()); err == nil {
+		t.Fatal("expected an error for an unsupported baud rate")
+	}
+	file, err := os.CreateTemp(t.TempDir(), "tty")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	if err := NewSerial(Serial{Path: file.Name()}).Send(context.Background(), "^XA^XZ"); err == nil {
+		t.Fatal("expected an error for a file which isn't a serial port")
+	}
+}
+
+func TestDevice(t *testing.T) {
+	master, path := openPTY(t)
+	respondPTY(master, map[string]string{"~HI": "\x02ZD420-300dpi,V84.20.18Z,12,8192KB\x03\r\n"})
+
+	// like `stty raw`, the line discipline would drop the framing characters
+	c, err := Serial{Path: path}.Open(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.Close()
+
+	p := NewDevice(path)
+	p.ReadTimeout = time.Second
+	id, err := p.Identify(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if id.Model != "ZD420-300dpi" {
+		t.Fatalf("unexpected identity %+v", id)
+	}
+}
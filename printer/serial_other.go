@@ -0,0 +1,15 @@
+//go:build !linux
+// +build !linux
+
+package printer
+
+import (
+	"errors"
+	"runtime"
+)
+
+// openSerial fails, serial ports are configured on Linux only. Ports set
+// up by other means can be used as Device.
+func openSerial(s Serial) (Conn, error) {
+	return nil, errors.New("serial ports aren't supported on " + runtime.GOOS)
+}
@@ -0,0 +1,156 @@
+package printer
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"sort"
+	"strings"
+)
+
+// SGD (Set/Get/Do) commands read and change the settings of a printer by
+// their name, like "device.friendly_name" or "print.tone".
+
+// Setting is a setting of the printer as listed by AllConfig
+type Setting struct {
+	Value string `json:"value"`
+	Type  string `json:"type"`
+	// Range lists the allowed values or limits, e.g. "on,off" or "0-30"
+	Range  string `json:"range"`
+	Access string `json:"access"`
+}
+
+// sgdCommand returns the SGD command, the arguments are quoted
+func sgdCommand(command string, args ...string) (string, error) {
+	cmd := "! U1 " + command
+	for _, arg := range args {
+		if strings.ContainsAny(arg, "\"\r\n") {
+			return "", fmt.Errorf("sgd: invalid argument %q", arg)
+		}
+		cmd += ` "` + arg + `"`
+	}
+	return cmd, nil
+}
+
+// readQuoted reads a quoted response and returns the text between the quotes
+func (c *conn) readQuoted() (string, error) {
+	if err := c.setDeadline("read", c.SetReadDeadline, c.p.ReadTimeout); err != nil {
+		return "", err
+	}
+	if _, err := c.reader.ReadString('"'); err != nil {
+		return "", c.opErr("read", err)
+	}
+	value, err := c.reader.ReadString('"')
+	if err != nil {
+		return "", c.opErr("read", err)
+	}
+	return strings.TrimSuffix(value, `"`), nil
+}
+
+// GetVars reads the settings of the printer (getvar) with a single connection.
+// Settings the printer doesn't know are an error.
+func (p *Printer) GetVars(ctx context.Context, names ...string) (map[string]string, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	values := make(map[string]string, len(names))
+	for _, name := range names {
+		value, err := c.getVar(name)
+		if err != nil {
+			return nil, err
+		}
+		if value == "?" {
+			return nil, fmt.Errorf("sgd: unknown setting %q", name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// getVar reads a setting on the connection, "?" if the printer doesn't know it
+func (c *conn) getVar(name string) (string, error) {
+	cmd, err := sgdCommand("getvar", name)
+	if err != nil {
+		return "", err
+	}
+	if err := c.send(cmd); err != nil {
+		return "", err
+	}
+	return c.readQuoted()
+}
+
+// GetVar reads a setting of the printer (getvar)
+func (p *Printer) GetVar(ctx context.Context, name string) (string, error) {
+	values, err := p.GetVars(ctx, name)
+	if err != nil {
+		return "", err
+	}
+	return values[name], nil
+}
+
+// SetVars changes the settings of the printer (setvar) with a single
+// connection, in the order of their names
+func (p *Printer) SetVars(ctx context.Context, values map[string]string) error {
+	names := make([]string, 0, len(values))
+	for name := range values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var cmds []string
+	for _, name := range names {
+		cmd, err := sgdCommand("setvar", name, values[name])
+		if err != nil {
+			return err
+		}
+		cmds = append(cmds, cmd)
+	}
+	return p.Send(ctx, strings.Join(cmds, "\r\n"))
+}
+
+// SetVar changes a setting of the printer (setvar)
+func (p *Printer) SetVar(ctx context.Context, name, value string) error {
+	return p.SetVars(ctx, map[string]string{name: value})
+}
+
+// Do executes an action of the printer, like "device.reset"
+func (p *Printer) Do(ctx context.Context, name, value string) error {
+	cmd, err := sgdCommand("do", name, value)
+	if err != nil {
+		return err
+	}
+	return p.Send(ctx, cmd)
+}
+
+// AllConfig reads all settings of the printer with the JSON variant of SGD
+func (p *Printer) AllConfig(ctx context.Context) (map[string]Setting, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+
+	if err := c.send(`{}{"allconfig":null}`); err != nil {
+		return nil, err
+	}
+	if err := c.setDeadline("read", c.SetReadDeadline, c.p.ReadTimeout); err != nil {
+		return nil, err
+	}
+	var response struct {
+		AllConfig map[string]Setting `json:"allconfig"`
+	}
+	if err := json.NewDecoder(c.reader).Decode(&response); err != nil {
+		var ne net.Error
+		if err == io.EOF || errors.As(err, &ne) {
+			return nil, c.opErr("read", err)
+		}
+		return nil, fmt.Errorf("sgd: invalid allconfig response: %w", err)
+	}
+	return response.AllConfig, nil
+}
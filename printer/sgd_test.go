@@ -0,0 +1,96 @@
+package printer
+
+import (
+	"bufio"
+	"context"
+	"io"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// sgdServer emulates the SGD commands of a printer with the settings in vars,
+// the names of changed settings and actions are sent to changed
+func sgdServer(vars map[string]string, changed chan<- string) func(c net.Conn) {
+	var mu sync.Mutex
+	return func(c net.Conn) {
+		scanner := bufio.NewScanner(c)
+		for scanner.Scan() {
+			line := scanner.Text()
+			mu.Lock()
+			if line == `{}{"allconfig":null}` {
+				io.WriteString(c, `{"allconfig":{"device.friendly_name":{"value":"`+vars["device.friendly_name"]+
+					`","type":"string","range":"0-17","access":"RW"},"print.tone":{"value":"10.0","type":"double","range":"0.0-30.0","access":"RW"}}}`)
+			}
+			args := strings.Split(line, `"`)
+			switch strings.TrimSpace(args[0]) {
+			case "! U1 getvar":
+				value, ok := vars[args[1]]
+				if !ok {
+					value = "?"
+				}
+				io.WriteString(c, `"`+value+`"`)
+			case "! U1 setvar":
+				vars[args[1]] = args[3]
+				changed <- args[1]
+			case "! U1 do":
+				changed <- "do " + args[1]
+			}
+			mu.Unlock()
+		}
+	}
+}
+
+func TestGetSetVar(t *testing.T) {
+	vars := map[string]string{"device.friendly_name": "ZT410", "print.tone": "10.0"}
+	changed := make(chan string, 2)
+	p := serve(t, sgdServer(vars, changed))
+	ctx := context.Background()
+
+	name, err := p.GetVar(ctx, "device.friendly_name")
+	if err != nil || name != "ZT410" {
+		t.Fatalf("GetVar = %q, %v", name, err)
+	}
+	if _, err := p.GetVar(ctx, "no.such.setting"); err == nil {
+		t.Fatal("expected an error for an unknown setting")
+	}
+
+	if err := p.SetVars(ctx, map[string]string{"print.tone": "15.0", "media.type": "label"}); err != nil {
+		t.Fatal(err)
+	}
+	<-changed
+	<-changed
+	values, err := p.GetVars(ctx, "print.tone", "media.type")
+	want := map[string]string{"print.tone": "15.0", "media.type": "label"}
+	if err != nil || !reflect.DeepEqual(values, want) {
+		t.Fatalf("GetVars = %v, %v", values, err)
+	}
+
+	if err := p.SetVar(ctx, "device.friendly_name", `"quoted"`); err == nil {
+		t.Fatal("expected an error for a value with quotes")
+	}
+	if err := p.Do(ctx, "device.reset", ""); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-changed; got != "do device.reset" {
+		t.Fatalf("expected the action, got %q", got)
+	}
+}
+
+func TestAllConfig(t *testing.T) {
+	p := serve(t, sgdServer(map[string]string{"device.friendly_name": "ZT410"}, nil))
+	config, err := p.AllConfig(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(config) != 2 || config["device.friendly_name"].Value != "ZT410" || config["print.tone"].Range != "0.0-30.0" {
+		t.Fatalf("unexpected allconfig %+v", config)
+	}
+
+	p = serve(t, respond(map[string]string{`{}{"allconfig":null}`: "not json"}))
+	if _, err := p.AllConfig(context.Background()); err == nil || !strings.Contains(err.Error(), "invalid allconfig") {
+		t.Fatalf("expected an invalid response error, got %v", err)
+	}
+}
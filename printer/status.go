@@ -0,0 +1,184 @@
+package printer
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// HostStatus is the state of a printer as reported by ~HS (Host Status Return)
+type HostStatus struct {
+	PaperOut         bool
+	Paused           bool
+	LabelLength      int // in dots
+	FormatsInBuffer  int
+	BufferFull       bool
+	DiagnosticMode   bool
+	PartialFormat    bool
+	CorruptRAM       bool
+	UnderTemperature bool
+	OverTemperature  bool // the head is too hot
+
+	HeadOpen        bool
+	RibbonOut       bool
+	ThermalTransfer bool
+	PrintMode       PrintMode
+	LabelWaiting    bool // a label waits to be taken in peel-off mode
+	LabelsRemaining int  // labels remaining in the batch
+	GraphicsStored  int  // graphic images stored in memory
+
+	StaticRAM bool
+}
+
+// PrintMode is the print mode reported by ~HS
+type PrintMode int
+
+// Print modes
+const (
+	Rewind PrintMode = iota
+	PeelOff
+	TearOff
+	Cutter
+	Applicator
+	DelayedCut
+	Linerless
+	RFID
+	Kiosk
+)
+
+func (pm PrintMode) String() string {
+	names := []string{"rewind", "peel-off", "tear-off", "cutter", "applicator", "delayed cut", "linerless", "RFID", "kiosk"}
+	if pm >= 0 && int(pm) < len(names) {
+		return names[pm]
+	}
+	return "unknown (" + strconv.Itoa(int(pm)) + ")"
+}
+
+// Problems returns the names of all flags which keep the printer from
+// printing, an empty result means the printer is ready
+func (s *HostStatus) Problems() []string {
+	var problems []string
+	for _, f := range []struct {
+		set  bool
+		name string
+	}{
+		{s.PaperOut, "paper out"},
+		{s.Paused, "paused"},
+		{s.BufferFull, "buffer full"},
+		{s.CorruptRAM, "corrupt RAM"},
+		{s.UnderTemperature, "head too cold"},
+		{s.OverTemperature, "head too hot"},
+		{s.HeadOpen, "head open"},
+		{s.RibbonOut, "ribbon out"},
+	} {
+		if f.set {
+			problems = append(problems, f.name)
+		}
+	}
+	return problems
+}
+
+// Ready reports whether none of the flags keeping the printer from printing is set
+func (s *HostStatus) Ready() bool {
+	return len(s.Problems()) == 0
+}
+
+// responseFields splits a response framed by STX and ETX into its comma
+// separated fields
+func responseFields(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimPrefix(line, "\x02")
+	line = strings.TrimSuffix(line, "\x03")
+	return strings.Split(line, ",")
+}
+
+// fieldParser converts fields of a response and keeps the first error
+type fieldParser struct {
+	fields []string
+	err    error
+}
+
+func (fp *fieldParser) int(i int) int {
+	if fp.err != nil {
+		return 0
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(fp.fields[i]))
+	if err != nil {
+		fp.err = fmt.Errorf("field %d: %w", i+1, err)
+	}
+	return n
+}
+
+func (fp *fieldParser) flag(i int) bool {
+	return fp.int(i) != 0
+}
+
+// ParseHostStatus parses the three lines a printer responds to ~HS with
+func ParseHostStatus(response string) (*HostStatus, error) {
+	lines := strings.Split(strings.TrimSpace(response), "\n")
+	if len(lines) < 2 {
+		return nil, fmt.Errorf("host status: expected 3 lines, got %d", len(lines))
+	}
+	first, second := responseFields(lines[0]), responseFields(lines[1])
+	if len(first) < 12 || len(second) < 11 {
+		return nil, fmt.Errorf("host status: unexpected response %q", response)
+	}
+
+	var s HostStatus
+	fp := &fieldParser{fields: first}
+	s.PaperOut = fp.flag(1)
+	s.Paused = fp.flag(2)
+	s.LabelLength = fp.int(3)
+	s.FormatsInBuffer = fp.int(4)
+	s.BufferFull = fp.flag(5)
+	s.DiagnosticMode = fp.flag(6)
+	s.PartialFormat = fp.flag(7)
+	s.CorruptRAM = fp.flag(9)
+	s.UnderTemperature = fp.flag(10)
+	s.OverTemperature = fp.flag(11)
+	if fp.err != nil {
+		return nil, fmt.Errorf("host status line 1: %w", fp.err)
+	}
+
+	fp = &fieldParser{fields: second}
+	s.HeadOpen = fp.flag(2)
+	s.RibbonOut = fp.flag(3)
+	s.ThermalTransfer = fp.flag(4)
+	s.PrintMode = PrintMode(fp.int(5))
+	s.LabelWaiting = fp.flag(7)
+	s.LabelsRemaining = fp.int(8)
+	s.GraphicsStored = fp.int(10)
+	if fp.err != nil {
+		return nil, fmt.Errorf("host status line 2: %w", fp.err)
+	}
+
+	if len(lines) > 2 {
+		if third := responseFields(lines[2]); len(third) > 1 {
+			s.StaticRAM = strings.TrimSpace(third[1]) == "1"
+		}
+	}
+	return &s, nil
+}
+
+// status queries the state of the printer via ~HS on the connection
+func (c *conn) status() (*HostStatus, error) {
+	if err := c.send("~HS"); err != nil {
+		return nil, err
+	}
+	response, err := c.readLines(3)
+	if err != nil {
+		return nil, err
+	}
+	return ParseHostStatus(response)
+}
+
+// Status queries the state of the printer via ~HS
+func (p *Printer) Status(ctx context.Context) (*HostStatus, error) {
+	c, err := p.dial(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer c.Close()
+	return c.status()
+}
@@ -0,0 +1,56 @@
+package printer
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+const hostStatus = "\x02030,1,0,1245,003,0,0,0,000,0,0,1\x03\r\n" +
+	"\x02001,0,1,0,1,2,4,0,00000012,1,002\x03\r\n" +
+	"\x021234,1\x03\r\n"
+
+func TestParseHostStatus(t *testing.T) {
+	got, err := ParseHostStatus(hostStatus)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &HostStatus{
+		PaperOut:        true,
+		LabelLength:     1245,
+		FormatsInBuffer: 3,
+		OverTemperature: true,
+		HeadOpen:        true,
+		ThermalTransfer: true,
+		PrintMode:       TearOff,
+		LabelsRemaining: 12,
+		GraphicsStored:  2,
+		StaticRAM:       true,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("ParseHostStatus = %+v, want %+v", got, want)
+	}
+	if got.Ready() {
+		t.Fatal("expected printer with paper out not to be ready")
+	}
+	if problems := got.Problems(); !reflect.DeepEqual(problems, []string{"paper out", "head too hot", "head open"}) {
+		t.Fatalf("unexpected problems %v", problems)
+	}
+
+	for _, invalid := range []string{"", "\x02030,1\x03\r\n\x02001\x03\r\n", "\x02030,x,0,1245,003,0,0,0,000,0,0,1\x03\r\n\x02001,0,1,0,1,2,4,0,00000012,1,002\x03\r\n"} {
+		if _, err := ParseHostStatus(invalid); err == nil {
+			t.Errorf("expected an error for %q", invalid)
+		}
+	}
+}
+
+func TestStatus(t *testing.T) {
+	p := serve(t, respond(map[string]string{"~HS": hostStatus}))
+	status, err := p.Status(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !status.PaperOut || status.LabelsRemaining != 12 || status.PrintMode.String() != "tear-off" {
+		t.Fatalf("unexpected status %+v", status)
+	}
+}
@@ -0,0 +1,35 @@
+package printer
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"os"
+)
+
+// TLSPort is the raw TLS port of Link-OS printers
+const TLSPort = "9143"
+
+// NewTLSConfig returns a TLS configuration trusting the certificate
+// authorities in the PEM file caFile in addition to the system ones. An
+// empty caFile only uses the system authorities. Printers usually have self
+// signed certificates, insecureSkipVerify disables their verification.
+func NewTLSConfig(caFile string, insecureSkipVerify bool) (*tls.Config, error) {
+	config := &tls.Config{InsecureSkipVerify: insecureSkipVerify}
+	if caFile == "" {
+		return config, nil
+	}
+	pem, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, errors.New("no certificates found in " + caFile)
+	}
+	config.RootCAs = pool
+	return config, nil
+}
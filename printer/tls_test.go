@@ -0,0 +1,109 @@
+package printer
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/pem"
+	"errors"
+	"net"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// serveTLS starts a TLS server with the certificate of httptest and returns
+// its address and the certificate as PEM file
+func serveTLS(t *testing.T, handle func(c net.Conn)) (string, string) {
+	t.Helper()
+	cert := httptest.NewUnstartedServer(nil)
+	cert.StartTLS()
+	cert.Close()
+
+	l, err := tls.Listen("tcp", "127.0.0.1:0", cert.TLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+	go func() {
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer c.Close()
+				handle(c)
+			}()
+		}
+	}()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return l.Addr().String(), caFile
+}
+
+func TestTLS(t *testing.T) {
+	addr, caFile := serveTLS(t, respond(map[string]string{"~HI": "\x02ZT610-300dpi,V92.21.15Z,12,8192KB\x03\r\n"}))
+	ctx := context.Background()
+
+	p := New(addr)
+	p.ReadTimeout = time.Second
+	var err error
+	if p.TLSConfig, err = NewTLSConfig(caFile, false); err != nil {
+		t.Fatal(err)
+	}
+	if id, err := p.Identify(ctx); err != nil || id.Model != "ZT610-300dpi" {
+		t.Fatalf("Identify = %+v, %v", id, err)
+	}
+
+	p.TLSConfig, _ = NewTLSConfig("", false)
+	var perr *Error
+	if _, err := p.Identify(ctx); !errors.As(err, &perr) || perr.Op != "dial" {
+		t.Fatalf("expected the unknown certificate to fail, got %v", err)
+	}
+	p.TLSConfig, _ = NewTLSConfig("", true)
+	if _, err := p.Identify(ctx); err != nil {
+		t.Fatalf("expected skipping the verification to work, got %v", err)
+	}
+
+	if _, err := NewTLSConfig(filepath.Join(t.TempDir(), "missing.pem"), false); err == nil {
+		t.Fatal("expected an error for a missing CA file")
+	}
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("no certificate"), 0o644)
+	if _, err := NewTLSConfig(empty, false); err == nil {
+		t.Fatal("expected an error for a CA file without certificates")
+	}
+}
+
+func TestIPv6(t *testing.T) {
+	l, err := net.Listen("tcp6", "[::1]:0")
+	if err != nil {
+		t.Skipf("no IPv6: %v", err)
+	}
+	received := make(chan string, 1)
+	go func() {
+		c, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer c.Close()
+		buf := make([]byte, 16)
+		n, _ := c.Read(buf)
+		received <- string(buf[:n])
+	}()
+	defer l.Close()
+
+	_, port, _ := net.SplitHostPort(l.Addr().String())
+	if err := New(net.JoinHostPort("::1", port)).Send(context.Background(), "~JA"); err != nil {
+		t.Fatal(err)
+	}
+	if got := <-received; got != "~JA\r\n\r\n" {
+		t.Fatalf("printer received %q", got)
+	}
+}
@@ -0,0 +1,127 @@
+package printer
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"os"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// Conn is an open connection to a printer. Transports which don't support
+// deadlines return an error from the Set methods, the timeouts of the
+// Printer don't apply to them then.
+type Conn interface {
+	io.ReadWriteCloser
+	SetDeadline(t time.Time) error
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// Transport opens connections to a printer, other than the default raw TCP port
+type Transport interface {
+	Open(ctx context.Context) (Conn, error)
+}
+
+// Device is a Transport to a printer attached as a device file, like the
+// USB printer /dev/usb/lp0. Responses are read back if the device allows.
+type Device struct {
+	Path string
+}
+
+// Open opens the device file for reading and writing, or writing only if
+// reading isn't permitted
+func (d Device) Open(ctx context.Context) (Conn, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	// non-blocking files support deadlines if the device can be polled
+	f, err := os.OpenFile(d.Path, os.O_RDWR|syscall.O_NONBLOCK, 0)
+	if errors.Is(err, os.ErrPermission) || errors.Is(err, syscall.EINVAL) {
+		f, err = os.OpenFile(d.Path, os.O_WRONLY|syscall.O_NONBLOCK, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// NewDevice returns a Printer for the device file at path with the default timeouts
+func NewDevice(path string) *Printer {
+	p := New(path)
+	p.Transport = Device{Path: path}
+	return p
+}
+
+// ErrNoResponses is returned when reading from a transport which can only
+// submit jobs, like IPP and LPD
+var ErrNoResponses = errors.New("the transport doesn't support responses")
+
+// jobConn collects the data written to a job based transport and submits
+// it when closed. The write deadline and the context of the operation limit
+// the submission.
+type jobConn struct {
+	buf bytes.Buffer
+	// mu guards the deadline, which is set when the context is done
+	mu       sync.Mutex
+	deadline time.Time
+	submit   func(ctx context.Context, data []byte) error
+	closed   bool
+}
+
+func (c *jobConn) Read(b []byte) (int, error) {
+	return 0, ErrNoResponses
+}
+
+func (c *jobConn) Write(b []byte) (int, error) {
+	if deadline := c.writeDeadline(); !deadline.IsZero() && time.Now().After(deadline) {
+		return 0, os.ErrDeadlineExceeded
+	}
+	return c.buf.Write(b)
+}
+
+// Close submits the job, unless nothing was written
+func (c *jobConn) Close() error {
+	return c.closeContext(context.Background())
+}
+
+// closeContext submits the job like Close, canceled when ctx is done
+func (c *jobConn) closeContext(ctx context.Context) error {
+	if c.closed {
+		return nil
+	}
+	c.closed = true
+	if c.buf.Len() == 0 {
+		return nil
+	}
+	if deadline := c.writeDeadline(); !deadline.IsZero() {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, deadline)
+		defer cancel()
+	}
+	return c.submit(ctx, c.buf.Bytes())
+}
+
+func (c *jobConn) SetDeadline(t time.Time) error {
+	return c.SetWriteDeadline(t)
+}
+
+func (c *jobConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *jobConn) SetWriteDeadline(t time.Time) error {
+	c.mu.Lock()
+	c.deadline = t
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *jobConn) writeDeadline() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.deadline
+}
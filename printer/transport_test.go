@@ -0,0 +1,28 @@
+package printer
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestDeviceFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "lp0")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	p := NewDevice(path)
+	if err := p.Send(context.Background(), "^XA^FDlabel^FS^XZ"); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || string(data) != "^XA^FDlabel^FS^XZ\r\n\r\n" {
+		t.Fatalf("device received %q, %v", data, err)
+	}
+
+	err = NewDevice(filepath.Join(t.TempDir(), "missing")).Send(context.Background(), "^XA^XZ")
+	if perr, ok := err.(*Error); !ok || perr.Op != "dial" {
+		t.Fatalf("expected a dial error, got %v", err)
+	}
+}
@@ -0,0 +1,552 @@
+package zplgfa
+
+import (
+	"bufio"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/basicfont"
+	"golang.org/x/image/math/fixed"
+)
+
+// indexCommand returns the index of the first ^ command named name in data
+// (case insensitive), or -1. Binary data stays untouched, unlike with
+// bytes.ToUpper.
+func indexCommand(data []byte, name string) int {
+	for i := 0; i+len(name) < len(data); i++ {
+		if data[i] == '^' && strings.EqualFold(string(data[i+1:i+1+len(name)]), name) {
+			return i
+		}
+	}
+	return -1
+}
+
+// ScanFormats is a bufio.SplitFunc returning the formats (^XA to ^XZ) of a
+// ZPL stream. Commands outside of formats and an unfinished format at the
+// end of the stream are skipped.
+func ScanFormats(data []byte, atEOF bool) (advance int, token []byte, err error) {
+	start := indexCommand(data, "XA")
+	if start < 0 {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		// the end might be the beginning of a ^XA
+		if len(data) > 2 {
+			return len(data) - 2, nil, nil
+		}
+		return 0, nil, nil
+	}
+	end := indexCommand(data[start:], "XZ")
+	if end < 0 {
+		if atEOF {
+			return len(data), nil, nil
+		}
+		return start, nil, nil
+	}
+	end += start + 3
+	return end, data[start:end], nil
+}
+
+// SplitFormats splits a ZPL stream into its formats (^XA to ^XZ)
+func SplitFormats(zpl string) []string {
+	scanner := bufio.NewScanner(strings.NewReader(zpl))
+	scanner.Buffer(nil, len(zpl)+1)
+	scanner.Split(ScanFormats)
+	var formats []string
+	for scanner.Scan() {
+		formats = append(formats, scanner.Text())
+	}
+	return formats
+}
+
+// maxLabelSize is the largest width and height of a label in dots, the limit
+// of ^PW and ^LL
+const maxLabelSize = 32000
+
+// maxLabelDots limits the memory of a rendered label, which takes a byte per
+// dot
+const maxLabelDots = 64 << 20
+
+// checkLabelSize returns an error if a label of width × height dots can't be
+// rendered
+func checkLabelSize(width, height int) error {
+	if width < 1 || height < 1 || width > maxLabelSize || height > maxLabelSize {
+		return fmt.Errorf("invalid label size %dx%d", width, height)
+	}
+	if width*height > maxLabelDots {
+		return fmt.Errorf("label size %dx%d exceeds %d dots", width, height, maxLabelDots)
+	}
+	return nil
+}
+
+// checkPosition returns an error for coordinates beyond the limits of ZPL
+func checkPosition(x, y int) error {
+	if x < 0 || y < 0 || x > maxLabelSize || y > maxLabelSize {
+		return fmt.Errorf("invalid position %d,%d", x, y)
+	}
+	return nil
+}
+
+// UnsupportedError is returned by RenderZPL along with the label if the
+// format contains drawing commands which aren't rendered
+type UnsupportedError struct {
+	// Commands are the unsupported commands, e.g. ^BC
+	Commands []string
+}
+
+func (e *UnsupportedError) Error() string {
+	return "commands not rendered: " + strings.Join(e.Commands, ", ")
+}
+
+// renderer holds the state of a label while rendering its commands
+type renderer struct {
+	bm            *bitmap
+	width, height int
+
+	homeX, homeY  int
+	x, y          int
+	fieldTypeset  bool // ^FT: the origin is the bottom left corner
+	reverse       bool
+	hexIndicator  byte
+	barcode       bool
+	fontHeight    int
+	fontWidth     int
+	fieldData     *string
+	defaultHeight int
+	defaultWidth  int
+	unsupported   []string
+}
+
+// unsupport notes a drawing command which isn't rendered
+func (r *renderer) unsupport(command string) {
+	for _, c := range r.unsupported {
+		if c == command {
+			return
+		}
+	}
+	r.unsupported = append(r.unsupported, command)
+}
+
+// canvas returns the bitmap of the label, it is created with the first
+// drawing, so ^PW and ^LL apply
+func (r *renderer) canvas() *bitmap {
+	if r.bm == nil {
+		r.bm = newBitmap(r.width, r.height)
+	}
+	return r.bm
+}
+
+// paint sets a dot of the label, reversed fields (^FR) invert it instead
+func (r *renderer) paint(x, y int, black bool) {
+	bm := r.canvas()
+	if x < 0 || y < 0 || x >= bm.width || y >= bm.height {
+		return
+	}
+	if r.reverse {
+		if black {
+			bm.set(x, y, !bm.black(x, y))
+		}
+		return
+	}
+	bm.set(x, y, black)
+}
+
+// origin returns the top left corner of a field of the given height
+func (r *renderer) origin(height int) (int, int) {
+	if r.fieldTypeset {
+		return r.x, r.y - height
+	}
+	return r.x, r.y
+}
+
+// visible returns the part of a field of width × height dots at x0,y0 which
+// lies on the label, relative to the field, so huge fields render quickly
+func (r *renderer) visible(x0, y0, width, height int) image.Rectangle {
+	bm := r.canvas()
+	return image.Rect(0, 0, width, height).Intersect(image.Rect(-x0, -y0, bm.width-x0, bm.height-y0))
+}
+
+// intParams parses the comma separated numbers of params, empty or missing
+// parameters are set to their default
+func intParams(params string, defaults ...int) []int {
+	values := append([]int(nil), defaults...)
+	for i, p := range strings.Split(params, ",") {
+		if i >= len(values) {
+			break
+		}
+		if n, err := strconv.Atoi(strings.TrimSpace(p)); err == nil {
+			values[i] = n
+		}
+	}
+	return values
+}
+
+// RenderZPL rasterizes a ZPL label of width × height dots, ^PW and ^LL
+// override the size. Supported are the field commands (^FO, ^FT, ^LH, ^FR,
+// ^FH, ^FD, ^FS), graphic fields (^GF) in all formats this package creates,
+// graphic boxes (^GB) and text in a fixed bitmap font scaled to the size of
+// ^A and ^CF. Other commands are ignored, and if they would draw something,
+// barcodes among them, the label is returned with an *UnsupportedError.
+// Sizes beyond the limits of ZPL are an error.
+func RenderZPL(zpl string, width, height int) (image.Image, error) {
+	if err := checkLabelSize(width, height); err != nil {
+		return nil, err
+	}
+	r := &renderer{width: width, height: height, defaultHeight: 9}
+	caret, tilde := byte('^'), byte('~')
+	for i := 0; i < len(zpl); {
+		if zpl[i] != caret && zpl[i] != tilde {
+			i++
+			continue
+		}
+		if i+3 > len(zpl) {
+			return nil, fmt.Errorf("incomplete command at offset %d", i)
+		}
+		name := strings.ToUpper(zpl[i+1 : i+3])
+		if name == "CC" || name == "CT" {
+			if i+3 >= len(zpl) {
+				return nil, fmt.Errorf("missing prefix of %s at offset %d", name, i)
+			}
+			if name == "CC" {
+				caret = zpl[i+3]
+			} else {
+				tilde = zpl[i+3]
+			}
+			i += 4
+			continue
+		}
+
+		start := i + 3
+		end := start
+		if zpl[i] == caret && name == "GF" {
+			n, err := skipGraphicField(zpl[start:])
+			if err != nil {
+				return nil, fmt.Errorf("invalid ^GF at offset %d: %v", i, err)
+			}
+			if n < 0 || n > len(zpl)-start {
+				return nil, fmt.Errorf("invalid ^GF at offset %d", i)
+			}
+			end += n
+		}
+		for end < len(zpl) && zpl[end] != caret && zpl[end] != tilde {
+			end++
+		}
+		if zpl[i] == caret {
+			if err := r.command(name, zpl[start:end]); err != nil {
+				return nil, fmt.Errorf("^%s at offset %d: %v", name, i, err)
+			}
+		}
+		i = end
+	}
+	if len(r.unsupported) > 0 {
+		return r.canvas(), &UnsupportedError{Commands: r.unsupported}
+	}
+	return r.canvas(), nil
+}
+
+// command renders the ^ command name with its parameters
+func (r *renderer) command(name, params string) error {
+	switch {
+	case name[0] == 'A':
+		// ^A is followed by the font name and the orientation, e.g. ^A0N,30,20
+		if i := strings.IndexByte(params, ','); i >= 0 {
+			p := intParams(params[i+1:], r.defaultHeight, 0)
+			r.fontHeight, r.fontWidth = p[0], p[1]
+		}
+		return nil
+	case name[0] == 'B' && name != "BY":
+		r.barcode = true
+		r.unsupport("^" + name)
+		return nil
+	}
+
+	switch name {
+	case "XA":
+		r.homeX, r.homeY = 0, 0
+	case "PW", "LL":
+		width, height := r.width, r.height
+		if name == "PW" {
+			width = intParams(params, width)[0]
+		} else {
+			height = intParams(params, height)[0]
+		}
+		if r.bm != nil {
+			// the size of a label can't change once it is drawn on
+			return nil
+		}
+		if err := checkLabelSize(width, height); err != nil {
+			return err
+		}
+		r.width, r.height = width, height
+	case "LH":
+		p := intParams(params, 0, 0)
+		if err := checkPosition(p[0], p[1]); err != nil {
+			return err
+		}
+		r.homeX, r.homeY = p[0], p[1]
+	case "CF":
+		if i := strings.IndexByte(params, ','); i >= 0 {
+			p := intParams(params[i+1:], r.defaultHeight, 0)
+			r.defaultHeight, r.defaultWidth = p[0], p[1]
+		}
+	case "FO", "FT":
+		p := intParams(params, 0, 0)
+		if err := checkPosition(p[0], p[1]); err != nil {
+			return err
+		}
+		r.x, r.y = r.homeX+p[0], r.homeY+p[1]
+		r.fieldTypeset = name == "FT"
+	case "FR":
+		r.reverse = true
+	case "FH":
+		r.hexIndicator = '_'
+		// line breaks between commands aren't parameters
+		if params != "" && params[0] > ' ' {
+			r.hexIndicator = params[0]
+		}
+	case "FD", "FV":
+		data := params
+		r.fieldData = &data
+	case "FS":
+		if r.fieldData != nil && !r.barcode {
+			text, err := r.unhex(*r.fieldData)
+			if err != nil {
+				return err
+			}
+			r.text(text)
+		}
+		r.fieldData, r.reverse, r.hexIndicator, r.barcode = nil, false, 0, false
+		r.fontHeight, r.fontWidth = 0, 0
+	case "GB":
+		return r.box(params)
+	case "GF":
+		return r.graphicField(params)
+	case "GC", "GD", "GE", "GS", "IM", "XG":
+		r.unsupport("^" + name)
+	}
+	return nil
+}
+
+// unhex replaces the hexadecimal escapes of ^FH in field data
+func (r *renderer) unhex(data string) (string, error) {
+	if r.hexIndicator == 0 {
+		return data, nil
+	}
+	var sb strings.Builder
+	for i := 0; i < len(data); i++ {
+		if data[i] != r.hexIndicator {
+			sb.WriteByte(data[i])
+			continue
+		}
+		if i+3 > len(data) {
+			return "", fmt.Errorf("incomplete hex escape in %q", data)
+		}
+		b, err := hex.DecodeString(data[i+1 : i+3])
+		if err != nil {
+			return "", fmt.Errorf("invalid hex escape in %q", data)
+		}
+		sb.Write(b)
+		i += 2
+	}
+	return sb.String(), nil
+}
+
+// box renders a graphic box (^GB width,height,thickness,color)
+func (r *renderer) box(params string) error {
+	p := intParams(params, 1, 1, 1)
+	for _, n := range p {
+		if n < 0 || n > maxLabelSize {
+			return fmt.Errorf("invalid box size %q", params)
+		}
+	}
+	thickness := p[2]
+	if thickness < 1 {
+		thickness = 1
+	}
+	width, height := p[0], p[1]
+	if width < thickness {
+		width = thickness
+	}
+	if height < thickness {
+		height = thickness
+	}
+	black := true
+	if parts := strings.Split(params, ","); len(parts) > 3 && strings.TrimSpace(parts[3]) == "W" {
+		black = false
+	}
+
+	x0, y0 := r.origin(height)
+	v := r.visible(x0, y0, width, height)
+	for y := v.Min.Y; y < v.Max.Y; y++ {
+		for x := v.Min.X; x < v.Max.X; x++ {
+			if x < thickness || y < thickness || x >= width-thickness || y >= height-thickness {
+				r.paint(x0+x, y0+y, black)
+			}
+		}
+	}
+	return nil
+}
+
+// text renders text in the 7×13 dots basic font scaled to the font size
+func (r *renderer) text(text string) {
+	height, width := r.fontHeight, r.fontWidth
+	if height == 0 {
+		height, width = r.defaultHeight, r.defaultWidth
+	}
+	face := basicfont.Face7x13
+	if width == 0 {
+		width = height * face.Advance / face.Height
+	}
+	if height <= 0 || width <= 0 || text == "" {
+		return
+	}
+
+	glyphs := image.NewAlpha(image.Rect(0, 0, face.Advance*len([]rune(text)), face.Height))
+	d := font.Drawer{Dst: glyphs, Src: image.Black, Face: face, Dot: fixed.P(0, face.Ascent)}
+	d.DrawString(text)
+
+	// scale every glyph to width × height dots
+	b := glyphs.Bounds()
+	x0, y0 := r.origin(height)
+	v := r.visible(x0, y0, width*b.Dx()/face.Advance, height)
+	for y := v.Min.Y; y < v.Max.Y; y++ {
+		sy := y * b.Dy() / height
+		for x := v.Min.X; x < v.Max.X; x++ {
+			if glyphs.AlphaAt(x*face.Advance/width, sy).A >= 0x80 {
+				r.paint(x0+x, y0+y, true)
+			}
+		}
+	}
+}
+
+// graphicField renders a graphic field (^GF format,bytes,total,row bytes,data)
+func (r *renderer) graphicField(params string) error {
+	parts := strings.SplitN(params, ",", 5)
+	if len(parts) < 5 {
+		return fmt.Errorf("missing parameters")
+	}
+	p := intParams(strings.Join(parts[1:4], ","), 0, 0, 0)
+	size, total, rowBytes := p[0], p[1], p[2]
+	if rowBytes <= 0 || total < 0 || rowBytes > (maxLabelSize+7)/8 || total/rowBytes > maxLabelSize {
+		return fmt.Errorf("invalid size %d of %d bytes per row", total, rowBytes)
+	}
+
+	if size < 0 {
+		return fmt.Errorf("invalid byte count %d", size)
+	}
+
+	rows := total / rowBytes
+	x0, y0 := r.origin(rows)
+	v := r.visible(x0, y0, rowBytes*8, rows)
+	var data []byte
+	stride := rowBytes
+	switch strings.TrimSpace(parts[0]) {
+	case "B":
+		data = []byte(strings.TrimPrefix(parts[4], "\n"))
+		if len(data) > size {
+			data = data[:size]
+		}
+	case "A", "":
+		// only the part of the field on the label is decoded, a few bytes
+		// of compressed data could otherwise take lots of memory
+		stride = (v.Max.X + 7) / 8
+		var err error
+		data, err = decodeASCIIGraphic(parts[4], rowBytes, image.Pt(stride, v.Max.Y))
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unsupported format %q", parts[0])
+	}
+
+	for y := v.Min.Y; y < v.Max.Y; y++ {
+		for x := v.Min.X; x < v.Max.X; x++ {
+			i := y*stride + x/8
+			if i < len(data) && data[i]&(0x80>>uint(x%8)) != 0 {
+				r.paint(x0+x, y0+y, true)
+			}
+		}
+	}
+	return nil
+}
+
+// decodeASCIIGraphic decodes hexadecimal graphic field data, compressed
+// or not. The compression repeats the following hex digit by the counts
+// G-Y (1-19) and g-z (20-400), fills the rest of a row with zeros (,) or
+// ones (!) and repeats the previous row (:). Only the first size.Y rows of
+// rowBytes are decoded, of which the first size.X bytes are returned.
+func decodeASCIIGraphic(data string, rowBytes int, size image.Point) ([]byte, error) {
+	rowDigits, keep := rowBytes*2, size.X*2
+	out := make([]byte, 0, keep*size.Y)
+	// row holds the digits kept of the current row of length rowLen
+	var row, previous []byte
+	rowLen, rows := 0, 0
+	flush := func(fill byte) {
+		for len(row) < keep {
+			row = append(row, fill)
+		}
+		out = append(out, row...)
+		previous = append(previous[:0], row...)
+		row, rowLen = row[:0], 0
+		rows++
+	}
+	// add appends count times the digit c, without materializing the
+	// digits of huge counts beyond the kept ones
+	add := func(c byte, count int) {
+		for count > 0 && rows < size.Y {
+			n := rowDigits - rowLen
+			if count < n {
+				n = count
+			}
+			for i := rowLen; i < rowLen+n && i < keep; i++ {
+				row = append(row, c)
+			}
+			rowLen += n
+			count -= n
+			if rowLen == rowDigits {
+				flush('0')
+			}
+		}
+	}
+
+	count := 0
+	for i := 0; i < len(data) && rows < size.Y; i++ {
+		c := data[i]
+		switch {
+		case c >= 'G' && c <= 'Y':
+			count += int(c-'G') + 1
+		case c >= 'g' && c <= 'z':
+			count += (int(c-'g') + 1) * 20
+		case c >= '0' && c <= '9' || c >= 'A' && c <= 'F' || c >= 'a' && c <= 'f':
+			if count == 0 {
+				count = 1
+			}
+			add(c, count)
+			count = 0
+		case c == ',':
+			flush('0')
+		case c == '!':
+			flush('F')
+		case c == ':':
+			// the first row repeats zeros
+			row = append(row[:0], previous...)
+			flush('0')
+		case c == ' ' || c == '\n' || c == '\r' || c == '\t':
+		default:
+			return nil, fmt.Errorf("invalid character %q in graphic data", c)
+		}
+	}
+	if rowLen > 0 && rows < size.Y {
+		flush('0')
+	}
+
+	decoded := make([]byte, hex.DecodedLen(len(out)))
+	if _, err := hex.Decode(decoded, out); err != nil {
+		return nil, err
+	}
+	return decoded, nil
+}
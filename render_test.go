@@ -0,0 +1,170 @@
+package zplgfa
+
+import (
+	"encoding/hex"
+	"errors"
+	"image"
+	"image/color"
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// blackDots lists the black dots of img
+func blackDots(img image.Image) []image.Point {
+	bm := bitmapFromImage(img)
+	var dots []image.Point
+	for y := 0; y < bm.height; y++ {
+		for x := 0; x < bm.width; x++ {
+			if bm.black(x, y) {
+				dots = append(dots, image.Pt(x, y))
+			}
+		}
+	}
+	return dots
+}
+
+func Test_RenderZPLGraphicField(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	img := whiteImage(48, 21)
+	for i := 0; i < 400; i++ {
+		img.Set(rnd.Intn(48), rnd.Intn(21), color.Black)
+	}
+	fillRect(img, image.Rect(0, 10, 48, 13))
+
+	for _, gt := range []GraphicType{ASCII, Binary, CompressedASCII} {
+		label, err := RenderZPL(ConvertToZPL(img, gt), 64, 32)
+		if err != nil {
+			t.Fatalf("%v: %v", gt, err)
+		}
+		if label.Bounds() != image.Rect(0, 0, 64, 32) {
+			t.Fatalf("%v: unexpected size %v", gt, label.Bounds())
+		}
+		if got, want := blackDots(label), blackDots(img); !reflect.DeepEqual(got, want) {
+			t.Fatalf("%v: rendered %d black dots, want %d", gt, len(got), len(want))
+		}
+	}
+}
+
+func Test_RenderZPLFields(t *testing.T) {
+	label, err := RenderZPL("^XA^PW40^LL30^LH5,5^FO0,0^GB10,8,2^FS"+
+		"^FO20,0^GB8,8,8^FS^FO20,4^FR^GB8,2,2^FS^XZ", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bm := label.(*bitmap)
+	if bm.width != 40 || bm.height != 30 {
+		t.Fatalf("expected ^PW and ^LL to set the size, got %dx%d", bm.width, bm.height)
+	}
+	for _, c := range []struct {
+		x, y  int
+		black bool
+	}{
+		{5, 5, true}, {14, 12, true}, {8, 8, false}, // box border and inside
+		{25, 5, true}, {25, 9, false}, {25, 10, false}, {25, 11, true}, // reversed stripe
+		{4, 4, false}, // label home
+	} {
+		if bm.black(c.x, c.y) != c.black {
+			t.Errorf("dot %d,%d: expected black %v", c.x, c.y, c.black)
+		}
+	}
+
+	text, err := RenderZPL("^XA^FO10,10^A0N,26,14^FH\n^FDA_42^FS^BY2^FO0,50^BCN,50^FD123^FS^FO0,80^BQN^FDQA,1^FS^XZ", 100, 100)
+	var unsupported *UnsupportedError
+	if !errors.As(err, &unsupported) || !reflect.DeepEqual(unsupported.Commands, []string{"^BC", "^BQ"}) {
+		t.Fatalf("expected the barcodes to be reported, got %v", err)
+	}
+	dots := blackDots(text)
+	if len(dots) == 0 {
+		t.Fatal("expected the text to be rendered")
+	}
+	for _, p := range dots {
+		if !p.In(image.Rect(10, 10, 10+2*14, 10+26)) {
+			t.Fatalf("dot %v outside of the text field, barcodes aren't rendered", p)
+		}
+	}
+
+	if _, err := RenderZPL("^XA^FO0,0^GFA,4,4,1,0G0Z^FS^XZ", 8, 8); err == nil {
+		t.Fatal("expected an error for invalid graphic data")
+	}
+}
+
+func Test_DecodeASCIIGraphic(t *testing.T) {
+	for data, want := range map[string]string{
+		"FFFF00::":    "FFFF00FFFF00FFFF00",
+		"JF,!":        "FFFF00FFFFFF",
+		"I0FF\n,G1J0": "000FF0100000",
+	} {
+		got, err := decodeASCIIGraphic(data, 3, image.Pt(3, 3))
+		if err != nil {
+			t.Fatalf("%q: %v", data, err)
+		}
+		if hexGot := strings.ToUpper(hex.EncodeToString(got)); hexGot != want {
+			t.Errorf("%q: decoded %s, want %s", data, hexGot, want)
+		}
+	}
+
+	// decoding stops at the given rows and bytes
+	got, err := decodeASCIIGraphic("FFFF00::zzzzzzzzzzF", 3, image.Pt(3, 2))
+	if err != nil || len(got) != 6 {
+		t.Fatalf("decoded %d bytes, %v", len(got), err)
+	}
+	got, err = decodeASCIIGraphic("F0FF00::", 3, image.Pt(1, 3))
+	if hexGot := strings.ToUpper(hex.EncodeToString(got)); err != nil || hexGot != "F0F0F0" {
+		t.Fatalf("decoded %s, %v, want F0F0F0", hexGot, err)
+	}
+}
+
+func Test_RenderZPLLimits(t *testing.T) {
+	for _, zpl := range []string{
+		"^XA^PW-5^FO0,0^GB10,10,10^FS^XZ",
+		"^XA^LL0^XZ",
+		"^XA^PW32001^XZ",
+		"^XA^PW32000^LL32000^XZ",
+		"^XA^FO0,0^GB-10,10,1^FS^XZ",
+		"^XA^FO0,0^GB10,2000000000,1^FS^XZ",
+		"^XA^FO-1,0^GB10,10,1^FS^XZ",
+		"^XA^LH0,99999999999^XZ",
+		"^XA^FO0,0^GFA,2000000000,2000000000,1,zF^FS^XZ",
+		"^XA^FO0,0^GFA,8000,8000,8000,zF^FS^XZ",
+		"^XA^FO0,0^GFB,-100,10,1,\nab^FS^XZ",
+		"^XA^FO0,0^GFB,-5,10,1,ab^FS^XZ",
+		"^XA^FO0,0" + strings.Repeat("^FO0,0", 40) + "^GFB,-100,10,1,\nab^FS^XZ",
+	} {
+		if _, err := RenderZPL(zpl, 100, 100); err == nil {
+			t.Errorf("expected an error for %q", zpl)
+		}
+	}
+	if _, err := RenderZPL("^XA^XZ", -1, 100); err == nil {
+		t.Error("expected an error for a negative width")
+	}
+
+	// compressed data of huge graphic fields is only decoded for the label
+	label, err := RenderZPL("^XA^FO0,0^GFA,128000000,128000000,4000,"+
+		strings.Repeat("z", 20)+"F"+strings.Repeat(":", 31999)+"^FS^XZ", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dots := blackDots(label); len(dots) != 100*100 {
+		t.Fatalf("rendered %d black dots, want %d", len(dots), 100*100)
+	}
+
+	// fields reaching far beyond the label are clipped
+	label, err = RenderZPL("^XA^PW16^LL8^FO0,0^GB32000,32000,32000^FS"+
+		"^FO8,4^A0N,32000,32000^FDA^FS^XZ", 100, 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dots := blackDots(label); len(dots) != 16*8 {
+		t.Fatalf("rendered %d black dots, want %d", len(dots), 16*8)
+	}
+}
+
+func Test_SplitFormats(t *testing.T) {
+	formats := SplitFormats("~JA\r\n^XA^FO0,0^FDone^FS^XZ\n~HS^xa^fdtwo^fs^xz\n^XA^FDunfinished")
+	want := []string{"^XA^FO0,0^FDone^FS^XZ", "^xa^fdtwo^fs^xz"}
+	if !reflect.DeepEqual(formats, want) {
+		t.Fatalf("SplitFormats = %q, want %q", formats, want)
+	}
+}
@@ -0,0 +1,181 @@
+package zplgfa
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"math"
+)
+
+// Resampler scales img to width × height pixels
+type Resampler func(img image.Image, width, height int) image.Image
+
+// ResizeNearest scales img to width × height pixels using nearest-neighbour
+// sampling, which keeps the hard edges of barcodes, QR codes and pixel fonts
+func ResizeNearest(img image.Image, width, height int) image.Image {
+	b := img.Bounds()
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	if b.Empty() {
+		return dst
+	}
+	for y := 0; y < height; y++ {
+		sy := b.Min.Y + y*b.Dy()/height
+		for x := 0; x < width; x++ {
+			dst.Set(x, y, img.At(b.Min.X+x*b.Dx()/width, sy))
+		}
+	}
+	return dst
+}
+
+// ModuleWidth estimates the width of the narrowest bar or space (the module)
+// of the barcodes in img, by searching for the shortest run of equal dots
+// which makes up a noticeable share of all runs within the rows
+func ModuleWidth(img image.Image) int {
+	bm := bitmapFromImage(img)
+	runs := map[int]int{}
+	total := 0
+	for y := 0; y < bm.height; y++ {
+		start := 0
+		for x := 1; x <= bm.width; x++ {
+			if x < bm.width && bm.black(x, y) == bm.black(start, y) {
+				continue
+			}
+			// runs touching the edges may be cut off
+			if start > 0 && x < bm.width {
+				runs[x-start]++
+				total++
+			}
+			start = x
+		}
+	}
+	for n := 1; n <= bm.width; n++ {
+		if runs[n] > 0 && runs[n]*20 >= total {
+			return n
+		}
+	}
+	return 1
+}
+
+// SnapScale adjusts scale, so modules of moduleWidth dots end up as a whole
+// number of dots. Every bar then keeps its width relative to the others.
+func SnapScale(scale float64, moduleWidth int) float64 {
+	if moduleWidth < 1 {
+		return scale
+	}
+	dots := math.Round(scale * float64(moduleWidth))
+	if dots < 1 {
+		dots = 1
+	}
+	return dots / float64(moduleWidth)
+}
+
+const (
+	// barcodeBlockSize is the size of the squares BarcodeRegions classifies
+	barcodeBlockSize = 16
+	// barcodeMinTransitions is the minimal number of black/white changes in a
+	// square to count as a barcode
+	barcodeMinTransitions = 2 * barcodeBlockSize
+)
+
+// barcodeBlock reports whether the square r of img contains hard-edged black
+// and white structures only (no gray tones) and changes between black and
+// white often enough to be part of a barcode
+func barcodeBlock(img image.Image, r image.Rectangle) bool {
+	transitions := 0
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		var last color.Gray16
+		for x := r.Min.X; x < r.Max.X; x++ {
+			c, ok := shortcircuit(rgbaFromColor(img.At(x, y)))
+			if !ok {
+				return false
+			}
+			if x > r.Min.X && c != last {
+				transitions++
+			}
+			last = c
+		}
+	}
+	return transitions >= barcodeMinTransitions
+}
+
+// BarcodeRegions searches img for barcodes, QR codes and other hard-edged,
+// pure black and white areas, which need to be treated differently from
+// photos when resizing. The rectangles are in the coordinates of img.
+func BarcodeRegions(img image.Image) []image.Rectangle {
+	b := img.Bounds()
+	cols := (b.Dx() + barcodeBlockSize - 1) / barcodeBlockSize
+	rows := (b.Dy() + barcodeBlockSize - 1) / barcodeBlockSize
+	block := func(col, row int) image.Rectangle {
+		origin := b.Min.Add(image.Pt(col, row).Mul(barcodeBlockSize))
+		return image.Rectangle{origin, origin.Add(image.Pt(barcodeBlockSize, barcodeBlockSize))}.Intersect(b)
+	}
+
+	marked := make([]bool, cols*rows)
+	for row := 0; row < rows; row++ {
+		for col := 0; col < cols; col++ {
+			marked[row*cols+col] = barcodeBlock(img, block(col, row))
+		}
+	}
+
+	// merge neighbouring blocks to regions
+	var regions []image.Rectangle
+	for i := range marked {
+		if !marked[i] {
+			continue
+		}
+		var region image.Rectangle
+		stack := []int{i}
+		marked[i] = false
+		for len(stack) > 0 {
+			n := stack[len(stack)-1]
+			stack = stack[:len(stack)-1]
+			col, row := n%cols, n/cols
+			region = region.Union(block(col, row))
+			for _, d := range []image.Point{{-1, 0}, {1, 0}, {0, -1}, {0, 1}} {
+				c, r := col+d.X, row+d.Y
+				if c >= 0 && r >= 0 && c < cols && r < rows && marked[r*cols+c] {
+					marked[r*cols+c] = false
+					stack = append(stack, r*cols+c)
+				}
+			}
+		}
+		regions = append(regions, region)
+	}
+	return regions
+}
+
+// ResizeBarcodeSafe scales img by scale, without blurring barcodes. The scale
+// is snapped to whole multiples of the barcode module width, barcode regions
+// are resampled with nearest-neighbour sampling and all other (photo) regions
+// with the photo Resampler. Without a photo Resampler the whole image is
+// resampled via nearest-neighbour.
+func ResizeBarcodeSafe(img image.Image, scale float64, photo Resampler) image.Image {
+	regions := BarcodeRegions(img)
+	module := 0
+	for _, r := range regions {
+		if m := ModuleWidth(subImage(img, r)); module == 0 || m < module {
+			module = m
+		}
+	}
+	scale = SnapScale(scale, module)
+
+	b := img.Bounds()
+	width := int(math.Round(float64(b.Dx()) * scale))
+	height := int(math.Round(float64(b.Dy()) * scale))
+	if photo == nil {
+		return ResizeNearest(img, width, height)
+	}
+
+	dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+	scaled := photo(img, width, height)
+	draw.Draw(dst, dst.Bounds(), scaled, scaled.Bounds().Min, draw.Src)
+	for _, r := range regions {
+		r = r.Sub(b.Min)
+		target := image.Rect(
+			int(math.Round(float64(r.Min.X)*scale)), int(math.Round(float64(r.Min.Y)*scale)),
+			int(math.Round(float64(r.Max.X)*scale)), int(math.Round(float64(r.Max.Y)*scale)),
+		)
+		draw.Draw(dst, target, ResizeNearest(subImage(img, r.Add(b.Min)), target.Dx(), target.Dy()), image.Point{}, draw.Src)
+	}
+	return dst
+}
@@ -0,0 +1,105 @@
+package zplgfa
+
+import (
+	"image"
+	"image/color"
+	"math"
+	"testing"
+)
+
+// barcodeImage returns an image with a photo-like gray gradient on the left
+// and a barcode made of 1 and 2 module wide bars on the right
+func barcodeImage(module int) (*image.NRGBA, image.Rectangle) {
+	img := whiteImage(128+40*module, 64)
+	for y := 0; y < 64; y++ {
+		for x := 0; x < 128; x++ {
+			v := uint8(64 + x)
+			img.Set(x, y, color.NRGBA{v, v, v, 0xff})
+		}
+	}
+	pattern := "X.XX..X.X..XX.X.XX.X..X.XX.X.X..XX.X.X"
+	x := 128 + module
+	for _, c := range pattern {
+		if c == 'X' {
+			fillRect(img, image.Rect(x, 0, x+module, 64))
+		}
+		x += module
+	}
+	return img, image.Rect(128, 0, img.Bounds().Dx(), 64)
+}
+
+// barWidths returns the run lengths of equal dots in row y
+func barWidths(img image.Image, y int) []int {
+	b := img.Bounds()
+	var widths []int
+	last, run := isBlack(img.At(b.Min.X, y).RGBA()), 0
+	for x := b.Min.X; x < b.Max.X; x++ {
+		if black := isBlack(img.At(x, y).RGBA()); black != last {
+			widths = append(widths, run)
+			last, run = black, 0
+		}
+		run++
+	}
+	return append(widths, run)
+}
+
+func Test_ResizeNearest(t *testing.T) {
+	img, _ := barcodeImage(1)
+	for _, w := range barWidths(ResizeNearest(subImage(img, image.Rect(129, 0, 167, 64)), 114, 10), 5) {
+		if w%3 != 0 {
+			t.Fatalf("expected all bars to be multiples of 3 dots, got %d", w)
+		}
+	}
+}
+
+func Test_ModuleWidth(t *testing.T) {
+	for _, module := range []int{1, 2, 3} {
+		img, code := barcodeImage(module)
+		if got := ModuleWidth(subImage(img, code)); got != module {
+			t.Errorf("ModuleWidth = %d, want %d", got, module)
+		}
+	}
+}
+
+func Test_SnapScale(t *testing.T) {
+	if got := SnapScale(1.5, 3); math.Abs(got-5.0/3) > 1e-9 {
+		t.Fatalf("SnapScale(1.5, 3) = %f, want %f", got, 5.0/3)
+	}
+	if got := SnapScale(0.1, 2); got != 0.5 {
+		t.Fatalf("SnapScale(0.1, 2) = %f, want 0.5", got)
+	}
+}
+
+func Test_BarcodeRegions(t *testing.T) {
+	img, code := barcodeImage(2)
+	regions := BarcodeRegions(img)
+	if len(regions) != 1 {
+		t.Fatalf("expected one barcode region, got %v", regions)
+	}
+	if !regions[0].Overlaps(code) || regions[0].Min.X < 128 {
+		t.Fatalf("region %v doesn't match the barcode at %v", regions[0], code)
+	}
+}
+
+func Test_ResizeBarcodeSafe(t *testing.T) {
+	img, _ := barcodeImage(2)
+	// a "photo" resampler which blurs everything into gray
+	blurry := func(img image.Image, width, height int) image.Image {
+		dst := image.NewNRGBA(image.Rect(0, 0, width, height))
+		for i := range dst.Pix {
+			dst.Pix[i] = 0x80
+		}
+		return dst
+	}
+	resized := ResizeBarcodeSafe(img, 1.3, blurry)
+	// 1.3 is snapped to 1.5, so the 2 dots wide module becomes 3 dots
+	if got := resized.Bounds().Dx(); got != int(math.Round(float64(img.Bounds().Dx())*1.5)) {
+		t.Fatalf("unexpected width %d", got)
+	}
+	widths := barWidths(subImage(resized, image.Rect(200, 0, resized.Bounds().Dx(), 64)), 10)
+	for _, w := range widths[1 : len(widths)-1] {
+		if w != 3 && w != 6 {
+			t.Fatalf("expected bars of 3 or 6 dots, got %v", widths)
+		}
+	}
+}
@@ -0,0 +1,554 @@
+// Package spool persists print jobs on disk before they are delivered, so
+// they survive restarts of the process. Every printer has its own queue,
+// which delivers the jobs in the order they were enqueued.
+package spool
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/PaackEng/zplgfa"
+	"github.com/PaackEng/zplgfa/printer"
+)
+
+// State is the state of a job in the spool
+type State string
+
+// Job states
+const (
+	// Queued jobs wait for their delivery
+	Queued State = "queued"
+	// Sending jobs are being delivered
+	Sending State = "sending"
+	// Interrupted jobs were being delivered when the process stopped, or
+	// were sent without the printer confirming them. They may have been
+	// printed already, so they hold up the queue of their printer until they
+	// are retried or canceled.
+	Interrupted State = "interrupted"
+	// Failed jobs couldn't be sent within the attempts of the delivery
+	// options. They hold up the queue of their printer until they are
+	// retried or canceled.
+	Failed State = "failed"
+	// Done jobs were delivered
+	Done State = "done"
+	// Canceled jobs won't be delivered
+	Canceled State = "canceled"
+)
+
+// finished reports whether jobs in the state are done with
+func (st State) finished() bool {
+	return st == Done || st == Canceled
+}
+
+// Job is a print job in the spool
+type Job struct {
+	// ID increases with every enqueued job
+	ID      uint64    `json:"id"`
+	Printer string    `json:"printer"`
+	State   State     `json:"state"`
+	Created time.Time `json:"created"`
+	Updated time.Time `json:"updated"`
+	// Attempts is the number of times the job was sent
+	Attempts int `json:"attempts"`
+	// Delivery is the state reported by the printer once the job is done or
+	// sent without confirmation
+	Delivery printer.JobState `json:"delivery,omitempty"`
+	// Error is the error of the last failed attempt
+	Error string `json:"error,omitempty"`
+}
+
+// Errors of the spool
+var (
+	ErrNotFound       = errors.New("spool: job not found")
+	ErrUnknownPrinter = errors.New("spool: unknown printer")
+	ErrJobState       = errors.New("spool: the job can't be changed in its state")
+)
+
+// Options control the delivery of the jobs
+type Options struct {
+	// Delivery is passed to Printer.Deliver for a single attempt at a time.
+	// A job which didn't reach the printer is queued again until its
+	// Attempts are exhausted, then it fails.
+	Delivery printer.DeliveryOptions
+	// ResendInterrupted resends interrupted jobs instead of waiting for
+	// Retry or Cancel, which may print them twice
+	ResendInterrupted bool
+	// Retention is the time done and canceled jobs are kept after they
+	// finished, DefaultRetention if 0. Negative values keep them forever.
+	Retention time.Duration
+}
+
+// DefaultRetention is the time finished jobs are kept if not set
+const DefaultRetention = 24 * time.Hour
+
+// Spool is a directory of jobs for a set of named printers
+type Spool struct {
+	dir      string
+	printers map[string]*printer.Printer
+	opts     Options
+
+	mu     sync.Mutex
+	jobs   map[uint64]*Job
+	lastID uint64
+	// pending holds the unfinished jobs of every printer ordered by id
+	pending map[string][]*Job
+	// wake signals the queue of a printer that a job changed
+	wake map[string]chan struct{}
+}
+
+// Open opens the spool in dir, which is created if missing. Jobs that were
+// being sent when the spool was last used are marked as interrupted.
+func Open(dir string, printers map[string]*printer.Printer, opts Options) (*Spool, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Spool{
+		dir:      dir,
+		printers: printers,
+		opts:     opts,
+		jobs:     map[uint64]*Job{},
+		pending:  map[string][]*Job{},
+		wake:     map[string]chan struct{}{},
+	}
+	for name := range printers {
+		s.wake[name] = make(chan struct{}, 1)
+	}
+	if err := s.load(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// load reads the jobs in the directory and removes the leftovers of jobs
+// whose enqueuing didn't finish and the expired finished jobs
+func (s *Spool) load() error {
+	entries, err := os.ReadDir(s.dir)
+	if err != nil {
+		return err
+	}
+	data := map[uint64]string{}
+	for _, e := range entries {
+		name := e.Name()
+		id, err := strconv.ParseUint(strings.TrimSuffix(name, filepath.Ext(name)), 10, 64)
+		if err != nil {
+			continue
+		}
+		if id > s.lastID {
+			s.lastID = id
+		}
+		switch filepath.Ext(name) {
+		case ".zpl":
+			data[id] = name
+		case ".json":
+			job, err := s.readJob(name)
+			if err != nil {
+				return err
+			}
+			s.jobs[id] = job
+		}
+	}
+	for id, name := range data {
+		if _, ok := s.jobs[id]; !ok {
+			os.Remove(filepath.Join(s.dir, name))
+		}
+	}
+	ids := make([]uint64, 0, len(s.jobs))
+	for id, job := range s.jobs {
+		if job.State == Sending {
+			job.State = Interrupted
+			if err := s.save(job); err != nil {
+				return err
+			}
+		}
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		if job := s.jobs[id]; !job.State.finished() {
+			s.pending[job.Printer] = append(s.pending[job.Printer], job)
+		}
+	}
+	s.prune(time.Now())
+	return nil
+}
+
+func (s *Spool) readJob(name string) (*Job, error) {
+	b, err := os.ReadFile(filepath.Join(s.dir, name))
+	if err != nil {
+		return nil, err
+	}
+	var job Job
+	if err := json.Unmarshal(b, &job); err != nil {
+		return nil, fmt.Errorf("spool: invalid job %s: %w", name, err)
+	}
+	return &job, nil
+}
+
+func (s *Spool) path(id uint64, ext string) string {
+	return filepath.Join(s.dir, fmt.Sprintf("%016d%s", id, ext))
+}
+
+// writeFile replaces the file at path with data, so that either the old or
+// the new content survives a crash
+func writeFile(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	if err := os.Rename(f.Name(), path); err != nil {
+		return err
+	}
+	// persist the rename
+	if dir, err := os.Open(filepath.Dir(path)); err == nil {
+		dir.Sync()
+		dir.Close()
+	}
+	return nil
+}
+
+// save persists the job, s.mu has to be held
+func (s *Spool) save(job *Job) error {
+	job.Updated = time.Now()
+	b, err := json.MarshalIndent(job, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFile(s.path(job.ID, ".json"), b)
+}
+
+// notify wakes up the queue of the printer
+func (s *Spool) notify(name string) {
+	select {
+	case s.wake[name] <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue adds the ZPL data as job for the named printer. The job is on disk
+// when Enqueue returns.
+func (s *Spool) Enqueue(name, zpl string) (Job, error) {
+	if _, ok := s.printers[name]; !ok {
+		return Job{}, fmt.Errorf("%w %q", ErrUnknownPrinter, name)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.lastID++
+	now := time.Now()
+	job := &Job{ID: s.lastID, Printer: name, State: Queued, Created: now}
+	if err := writeFile(s.path(job.ID, ".zpl"), []byte(zpl)); err != nil {
+		return Job{}, err
+	}
+	if err := s.save(job); err != nil {
+		os.Remove(s.path(job.ID, ".zpl"))
+		return Job{}, err
+	}
+	s.jobs[job.ID] = job
+	s.pending[name] = append(s.pending[name], job)
+	s.notify(name)
+	return *job, nil
+}
+
+// EnqueueImage converts the image to a label with a graphic field and adds
+// it as job for the named printer
+func (s *Spool) EnqueueImage(name string, img image.Image, graphicType zplgfa.GraphicType) (Job, error) {
+	return s.Enqueue(name, zplgfa.ConvertToZPL(zplgfa.FlattenImage(img), graphicType))
+}
+
+// Job returns the job with the id
+func (s *Spool) Job(id uint64) (Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return Job{}, ErrNotFound
+	}
+	return *job, nil
+}
+
+// Jobs returns the jobs of the named printer ordered by their id, or the jobs
+// of all printers if name is empty
+func (s *Spool) Jobs(name string) []Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var jobs []Job
+	for _, job := range s.jobs {
+		if name == "" || job.Printer == name {
+			jobs = append(jobs, *job)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].ID < jobs[j].ID })
+	return jobs
+}
+
+// change changes the state of a job from one of the states in from
+func (s *Spool) change(id uint64, to State, from ...State) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	job, ok := s.jobs[id]
+	if !ok {
+		return ErrNotFound
+	}
+	for _, state := range from {
+		if job.State == state {
+			job.State = to
+			if err := s.save(job); err != nil {
+				job.State = state
+				return err
+			}
+			if to == Canceled {
+				os.Remove(s.path(id, ".zpl"))
+				s.unqueue(job)
+			}
+			s.notify(job.Printer)
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: %s", ErrJobState, job.State)
+}
+
+// Retry queues an interrupted or failed job again
+func (s *Spool) Retry(id uint64) error {
+	return s.change(id, Queued, Interrupted, Failed)
+}
+
+// Cancel cancels a queued, interrupted or failed job
+func (s *Spool) Cancel(id uint64) error {
+	return s.change(id, Canceled, Queued, Interrupted, Failed)
+}
+
+// unqueue removes a finished job from the pending jobs of its printer, s.mu
+// has to be held
+func (s *Spool) unqueue(job *Job) {
+	queue := s.pending[job.Printer]
+	for i, pending := range queue {
+		if pending == job {
+			s.pending[job.Printer] = append(queue[:i], queue[i+1:]...)
+			return
+		}
+	}
+}
+
+// retention returns the time finished jobs are kept, negative if forever
+func (s *Spool) retention() time.Duration {
+	if s.opts.Retention == 0 {
+		return DefaultRetention
+	}
+	return s.opts.Retention
+}
+
+// prune removes the jobs which finished longer than the retention ago, s.mu
+// has to be held. The last job is kept, so its id isn't used again after a
+// restart.
+func (s *Spool) prune(now time.Time) {
+	retention := s.retention()
+	if retention < 0 {
+		return
+	}
+	for id, job := range s.jobs {
+		if id == s.lastID || !job.State.finished() || now.Sub(job.Updated) < retention {
+			continue
+		}
+		if err := os.Remove(s.path(id, ".json")); err != nil && !os.IsNotExist(err) {
+			continue
+		}
+		os.Remove(s.path(id, ".zpl"))
+		delete(s.jobs, id)
+	}
+}
+
+// Run delivers the jobs until ctx is done. Jobs being sent at that time are
+// finished, limited by the timeouts of their printer, before Run returns.
+// Meanwhile the finished jobs are removed after their retention. Only one
+// Run may be active per directory.
+func (s *Spool) Run(ctx context.Context) error {
+	var wg sync.WaitGroup
+	if retention := s.retention(); retention > 0 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			s.pruneEvery(ctx, retention)
+		}()
+	}
+	for name, p := range s.printers {
+		wg.Add(1)
+		go func(name string, p *printer.Printer) {
+			defer wg.Done()
+			s.run(ctx, name, p)
+		}(name, p)
+	}
+	wg.Wait()
+	return ctx.Err()
+}
+
+// pruneEvery prunes the finished jobs until ctx is done, at least once a
+// minute
+func (s *Spool) pruneEvery(ctx context.Context, retention time.Duration) {
+	interval := retention
+	if interval > time.Minute {
+		interval = time.Minute
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			s.mu.Lock()
+			s.prune(now)
+			s.mu.Unlock()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// next returns the first unfinished job of the printer, nil if there's none
+// or it has to wait for Retry or Cancel
+func (s *Spool) next(name string) *Job {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	queue := s.pending[name]
+	if len(queue) == 0 {
+		return nil
+	}
+	if next := queue[0]; next.State != Failed && (next.State != Interrupted || s.opts.ResendInterrupted) {
+		return next
+	}
+	return nil
+}
+
+// run delivers the jobs of the printer one after the other
+func (s *Spool) run(ctx context.Context, name string, p *printer.Printer) {
+	backoff := s.opts.Delivery.Backoff
+	if backoff <= 0 {
+		backoff = printer.DefaultBackoff
+	}
+	maxBackoff := s.opts.Delivery.MaxBackoff
+	if maxBackoff <= 0 {
+		maxBackoff = printer.DefaultMaxBackoff
+	}
+	attempts := s.opts.Delivery.Attempts
+	if attempts <= 0 {
+		attempts = printer.DefaultAttempts
+	}
+
+	wait := backoff
+	// failures counts the failed attempts of the job with the id current
+	var current uint64
+	failures := 0
+	for {
+		job := s.next(name)
+		if job == nil {
+			// a job held up by Retry or Cancel gets all attempts again
+			current, failures = 0, 0
+			select {
+			case <-s.wake[name]:
+				continue
+			case <-ctx.Done():
+				return
+			}
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		if job.ID != current {
+			current, failures = job.ID, 0
+		}
+		if err := s.deliver(p, job, failures+1 >= attempts); err != nil {
+			failures++
+			select {
+			case <-time.After(wait):
+			case <-ctx.Done():
+				return
+			}
+			if wait *= 2; wait > maxBackoff {
+				wait = maxBackoff
+			}
+			continue
+		}
+		wait = backoff
+	}
+}
+
+// deliver makes one attempt to deliver the job. It isn't interrupted by the
+// context of Run, which would leave the job in an unknown state. The job
+// fails if the last attempt doesn't reach the printer.
+func (s *Spool) deliver(p *printer.Printer, job *Job, last bool) error {
+	s.mu.Lock()
+	if job.State != Queued && (job.State != Interrupted || !s.opts.ResendInterrupted) {
+		// canceled since next returned it
+		s.mu.Unlock()
+		return nil
+	}
+	id := job.ID
+	job.State = Sending
+	job.Attempts++
+	err := s.save(job)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	var delivery *printer.Delivery
+	zpl, err := os.ReadFile(s.path(id, ".zpl"))
+	if err != nil {
+		// sending it again won't help
+		last = true
+	} else {
+		opts := s.opts.Delivery
+		opts.Attempts = 1
+		delivery, err = p.Deliver(context.Background(), string(zpl), opts)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if delivery != nil {
+		job.Delivery = delivery.State
+	}
+	job.Error = ""
+	switch {
+	case err == nil:
+		job.State = Done
+	case errors.Is(err, printer.ErrUnconfirmed):
+		// the job may have been printed, even on its last attempt
+		job.State = Interrupted
+	case last:
+		job.State = Failed
+	default:
+		// nothing reached the printer
+		job.State = Queued
+	}
+	if err != nil {
+		job.Error = err.Error()
+	}
+	if serr := s.save(job); serr != nil && err == nil {
+		err = serr
+	}
+	if job.State == Done {
+		os.Remove(s.path(id, ".zpl"))
+		s.unqueue(job)
+	}
+	return err
+}
@@ -0,0 +1,349 @@
+package spool
+
+import (
+	"context"
+	"errors"
+	"image"
+	"image/color"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/PaackEng/zplgfa"
+	"github.com/PaackEng/zplgfa/printer"
+	"github.com/PaackEng/zplgfa/printer/printertest"
+)
+
+var testOptions = Options{Delivery: printer.DeliveryOptions{Backoff: 10 * time.Millisecond, PollInterval: 10 * time.Millisecond}}
+
+func newServer(t *testing.T) (*printertest.Server, map[string]*printer.Printer) {
+	t.Helper()
+	s := printertest.NewServer()
+	t.Cleanup(s.Close)
+	p := printer.New(s.Addr)
+	p.ReadTimeout = time.Second
+	return s, map[string]*printer.Printer{"zebra": p}
+}
+
+// run runs the spool until the jobs with the ids are done
+func run(t *testing.T, s *Spool, ids ...uint64) {
+	t.Helper()
+	runUntil(t, s, Done, ids...)
+}
+
+// runUntil runs the spool until the jobs with the ids are in the state
+func runUntil(t *testing.T, s *Spool, state State, ids ...uint64) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() { done <- s.Run(ctx) }()
+	defer func() {
+		cancel()
+		<-done
+	}()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for _, id := range ids {
+		for {
+			job, err := s.Job(id)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if job.State == state {
+				break
+			}
+			if time.Now().After(deadline) {
+				t.Fatalf("job %d isn't %s: %+v", id, state, job)
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+}
+
+func TestSpool(t *testing.T) {
+	server, printers := newServer(t)
+	dir := t.TempDir()
+	s, err := Open(dir, printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	first, err := s.Enqueue("zebra", "^XA^FDfirst^FS^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	img := image.NewGray(image.Rect(0, 0, 8, 1))
+	img.Set(0, 0, color.Black)
+	second, err := s.EnqueueImage("zebra", img, zplgfa.ASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.State != Queued || second.ID <= first.ID {
+		t.Fatalf("unexpected jobs %+v, %+v", first, second)
+	}
+	if _, err := s.Enqueue("other", "^XA^XZ"); !errors.Is(err, ErrUnknownPrinter) {
+		t.Fatalf("expected an unknown printer, got %v", err)
+	}
+
+	server.InjectFault(printertest.DropConnection, 1)
+	run(t, s, first.ID, second.ID)
+	jobs := server.Jobs()
+	if len(jobs) != 2 || jobs[0] != "^XA^FDfirst^FS^XZ" || !strings.Contains(jobs[1], "^GFA") {
+		t.Fatalf("printer received %q", jobs)
+	}
+	job, _ := s.Job(first.ID)
+	if job.Attempts != 2 || job.Delivery != printer.JobQueued || job.Error != "" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	// finished jobs aren't sent again after a restart
+	s, err = Open(dir, printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if jobs := s.Jobs(""); len(jobs) != 2 || jobs[1].State != Done || jobs[1].Delivery != printer.JobQueued {
+		t.Fatalf("unexpected jobs after reopening %+v", jobs)
+	}
+	third, err := s.Enqueue("zebra", "^XA^FDthird^FS^XZ")
+	if err != nil || third.ID != second.ID+1 {
+		t.Fatalf("Enqueue = %+v, %v", third, err)
+	}
+	run(t, s, third.ID)
+	if jobs := server.Jobs(); len(jobs) != 3 || jobs[2] != "^XA^FDthird^FS^XZ" {
+		t.Fatalf("printer received %q", jobs)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	server, printers := newServer(t)
+	dir := t.TempDir()
+	s, err := Open(dir, printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for _, zpl := range []string{"^XA^FD1^FS^XZ", "^XA^FD2^FS^XZ", "^XA^FD3^FS^XZ"} {
+		job, err := s.Enqueue("zebra", zpl)
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	// the process stopped while sending the first job
+	if err := s.change(ids[0], Sending, Queued); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Cancel(ids[2]); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = Open(dir, printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var states []State
+	for _, job := range s.Jobs("zebra") {
+		states = append(states, job.State)
+	}
+	if want := []State{Interrupted, Queued, Canceled}; !reflect.DeepEqual(states, want) {
+		t.Fatalf("states = %v, want %v", states, want)
+	}
+	if err := s.Cancel(ids[2]); !errors.Is(err, ErrJobState) {
+		t.Fatalf("expected the canceled job not to change, got %v", err)
+	}
+	if _, err := s.Job(42); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected a missing job, got %v", err)
+	}
+
+	// the interrupted job holds up the queue
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	if err := s.Run(ctx); !errors.Is(err, context.DeadlineExceeded) || len(server.Jobs()) != 0 {
+		t.Fatalf("Run = %v, printer received %q", err, server.Jobs())
+	}
+
+	if err := s.Retry(ids[0]); err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, ids[0], ids[1])
+	if jobs := server.Jobs(); !reflect.DeepEqual(jobs, []string{"^XA^FD1^FS^XZ", "^XA^FD2^FS^XZ"}) {
+		t.Fatalf("printer received %q", jobs)
+	}
+}
+
+func TestRetention(t *testing.T) {
+	_, printers := newServer(t)
+	dir := t.TempDir()
+	opts := testOptions
+	opts.Retention = 50 * time.Millisecond
+	s, err := Open(dir, printers, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var ids []uint64
+	for i := 0; i < 3; i++ {
+		job, err := s.Enqueue("zebra", "^XA^XZ")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ids = append(ids, job.ID)
+	}
+	if err := s.Cancel(ids[1]); err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, ids[0], ids[2])
+
+	// the finished jobs are removed, except the last one which keeps its id
+	// from being used again
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+	if jobs := s.Jobs(""); len(jobs) != 1 || jobs[0].ID != ids[2] {
+		t.Fatalf("unexpected jobs %+v", jobs)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("unexpected files %v, %v", entries, err)
+	}
+	s, err = Open(dir, printers, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job, err := s.Enqueue("zebra", "^XA^XZ"); err != nil || job.ID != ids[2]+1 {
+		t.Fatalf("Enqueue = %+v, %v", job, err)
+	}
+}
+
+func TestUnconfirmed(t *testing.T) {
+	server, printers := newServer(t)
+	server.SetPrintDelay(0)
+	s, err := Open(t.TempDir(), printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the label counter confirms a label printed right away
+	job, err := s.Enqueue("zebra", "^XA^FD1^FS^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, job.ID)
+	if job, _ = s.Job(job.ID); job.Delivery != printer.JobPrinted || job.Attempts != 1 || server.Printed() != 1 {
+		t.Fatalf("unexpected job %+v, printed %d", job, server.Printed())
+	}
+
+	// without it the job may have been printed, so it isn't sent again
+	server.SetVar("odometer.total_label_count", "?")
+	printers["zebra"].ReadTimeout = 50 * time.Millisecond
+	job, err = s.Enqueue("zebra", "^XA^FD2^FS^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	next, err := s.Enqueue("zebra", "^XA^FD3^FS^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	runUntil(t, s, Interrupted, job.ID)
+	// the interrupted job holds up the queue
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	s.Run(ctx)
+	job, _ = s.Job(job.ID)
+	if job.State != Interrupted || job.Attempts != 1 || job.Delivery != printer.JobSent || !strings.Contains(job.Error, "confirm") {
+		t.Fatalf("unexpected job %+v", job)
+	}
+	if server.Printed() != 2 || len(server.Jobs()) != 2 {
+		t.Fatalf("printed %d labels of %d jobs", server.Printed(), len(server.Jobs()))
+	}
+	if err := s.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	runUntil(t, s, Interrupted, next.ID)
+	if server.Printed() != 3 {
+		t.Fatalf("printed %d labels", server.Printed())
+	}
+}
+
+func TestFailed(t *testing.T) {
+	server, printers := newServer(t)
+	opts := testOptions
+	opts.Delivery.Attempts = 3
+	s, err := Open(t.TempDir(), printers, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.Enqueue("zebra", "^XA^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server.SetFault(printertest.DropConnection)
+	runUntil(t, s, Failed, job.ID)
+	if job, _ = s.Job(job.ID); job.Attempts != 3 || job.Error == "" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+
+	// failed jobs hold up the queue until they are retried
+	server.SetFault(printertest.NoFault)
+	if err := s.Retry(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	run(t, s, job.ID)
+	if job, _ = s.Job(job.ID); job.Attempts != 4 || len(server.Jobs()) != 1 {
+		t.Fatalf("unexpected job %+v, printer received %q", job, server.Jobs())
+	}
+
+	// a job sent without confirmation by its last attempt may have been
+	// printed, so it doesn't fail, which would allow a Retry
+	server.SetVar("odometer.total_label_count", "?")
+	server.SetPrintDelay(0)
+	printers["zebra"].ReadTimeout = 50 * time.Millisecond
+	job, err = s.Enqueue("zebra", "^XA^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.deliver(printers["zebra"], s.next("zebra"), true); !errors.Is(err, printer.ErrUnconfirmed) {
+		t.Fatalf("deliver = %v", err)
+	}
+	if job, _ = s.Job(job.ID); job.State != Interrupted || job.Attempts != 1 {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
+
+func TestCanceledWhileSending(t *testing.T) {
+	server, printers := newServer(t)
+	dir := t.TempDir()
+	s, err := Open(dir, printers, testOptions)
+	if err != nil {
+		t.Fatal(err)
+	}
+	job, err := s.Enqueue("zebra", "^XA^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the job is canceled between picking and sending it
+	next := s.next("zebra")
+	if err := s.Cancel(job.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.deliver(printers["zebra"], next, false); err != nil {
+		t.Fatal(err)
+	}
+	if job, _ = s.Job(job.ID); job.State != Canceled || job.Attempts != 0 || len(server.Jobs()) != 0 {
+		t.Fatalf("unexpected job %+v, printer received %q", job, server.Jobs())
+	}
+
+	// a job without its data fails right away
+	job, err = s.Enqueue("zebra", "^XA^XZ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(s.path(job.ID, ".zpl")); err != nil {
+		t.Fatal(err)
+	}
+	runUntil(t, s, Failed, job.ID)
+	if job, _ = s.Job(job.ID); job.Attempts != 1 || job.Error == "" {
+		t.Fatalf("unexpected job %+v", job)
+	}
+}
@@ -0,0 +1,219 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"os"
+	"strconv"
+	"strings"
+	"text/template"
+)
+
+// loadImage decodes the image file at path, the formats have to be
+// registered by the caller (e.g. by importing image/png)
+func loadImage(path string) (image.Image, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	img, _, err := image.Decode(file)
+	return img, err
+}
+
+// ZPL is trusted ZPL code, which RenderTemplate inserts without escaping
+type ZPL string
+
+// TemplateFuncs returns the functions available in ZPL templates:
+//
+//	gf         image file path or image.Image to a compressed ^GF element
+//	zplEscape  text to a ^FD field data command via EscapeFieldData
+//	mm         millimeters to dots at the given dpi
+//	code128    ^BC Code 128 barcode: {{code128 .Tracking 100}}
+//	ean13      ^BE EAN-13 barcode: {{ean13 .EAN 100}}
+//	qrcode     ^BQ QR code: {{qrcode .URL 5}}
+//
+// The barcode helpers include the field data, so they only need a ^FO before
+// them.
+func TemplateFuncs(dpi int) template.FuncMap {
+	return templateFuncs(dpi, func(v interface{}) string {
+		return EscapeFieldData(fmt.Sprint(v))
+	}, func(s string) interface{} {
+		return ZPL(s)
+	})
+}
+
+// templateFuncs returns the functions of TemplateFuncs, which create field
+// data commands via fieldData and return their ZPL as zpl(s)
+func templateFuncs(dpi int, fieldData func(v interface{}) string, zpl func(s string) interface{}) template.FuncMap {
+	return template.FuncMap{
+		"gf": func(src interface{}) (interface{}, error) {
+			var img image.Image
+			switch s := src.(type) {
+			case image.Image:
+				img = s
+			case string:
+				var err error
+				if img, err = loadImage(s); err != nil {
+					return nil, err
+				}
+			default:
+				return nil, fmt.Errorf("gf: unsupported image source %T", src)
+			}
+			return zpl(ConvertToGraphicField(img, CompressedASCII)), nil
+		},
+		"zplEscape": func(v interface{}) interface{} {
+			return zpl(fieldData(v))
+		},
+		"mm": func(mm float64) int {
+			return MMToDots(mm, dpi)
+		},
+		"code128": func(data interface{}, height int) interface{} {
+			return zpl(fmt.Sprintf("^BCN,%d,Y,N,N%s^FS", height, fieldData(data)))
+		},
+		"ean13": func(data interface{}, height int) interface{} {
+			return zpl(fmt.Sprintf("^BEN,%d,Y,N%s^FS", height, fieldData(data)))
+		},
+		"qrcode": func(data interface{}, magnification int) interface{} {
+			return zpl(fmt.Sprintf("^BQN,2,%d%s^FS", magnification, fieldData("QA,"+fmt.Sprint(data))))
+		},
+	}
+}
+
+// RenderTemplate executes the ZPL template text with data, using the
+// functions of TemplateFuncs, and validates the resulting ZPL via ValidateZPL.
+//
+// Like html/template, the values inserted by the template are escaped
+// depending on where they end up: within field data (^FD, ^FV) their ^ and ~
+// are hex escaped via ^FH, and non-ASCII text switches the field to UTF-8 via
+// ^CI28, after which the code page of the label is restored (^CI0 unless the
+// template sets another one). Values with ^ or ~ anywhere else are an error.
+// Values of type ZPL and the output of the functions are inserted as they
+// are. The Unicode noncharacters U+FDD0 and U+FDD1 are reserved for the
+// escaping, they are removed from the values and not allowed in text.
+func RenderTemplate(text string, data interface{}, dpi int) (string, error) {
+	if strings.ContainsAny(text, valueStart+valueEnd) {
+		return "", fmt.Errorf("template: text contains U+FDD0 or U+FDD1")
+	}
+	funcs := templateFuncs(dpi, func(v interface{}) string {
+		return "^FD" + markValue(v)
+	}, func(s string) interface{} {
+		return markedZPL(s)
+	})
+	funcs[valueEscaper] = escapeValue
+	tmpl, err := template.New("zpl").Funcs(funcs).Parse(text)
+	if err != nil {
+		return "", err
+	}
+	for _, t := range tmpl.Templates() {
+		if t.Tree != nil {
+			addEscaper(t.Tree.Root)
+		}
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return "", err
+	}
+	zpl, err := escapeValues(sb.String())
+	if err != nil {
+		return "", err
+	}
+	if err := ValidateZPL(zpl); err != nil {
+		return "", err
+	}
+	return zpl, nil
+}
+
+// ValidateZPL checks the structure of a ZPL stream: labels have to start with
+// ^XA and end with ^XZ, and field data (^FD, ^FV) has to be closed by ^FS
+// without any other command in between, which catches unescaped ^ and ~ in
+// field values. Prefix changes via ^CC and ^CT are followed.
+func ValidateZPL(zpl string) error {
+	caret, tilde := byte('^'), byte('~')
+	inLabel, inField := false, false
+	for i := 0; i < len(zpl); i++ {
+		if zpl[i] != caret && zpl[i] != tilde {
+			continue
+		}
+		if i+3 > len(zpl) {
+			return fmt.Errorf("incomplete command at offset %d", i)
+		}
+		prefix := "^"
+		if zpl[i] == tilde {
+			prefix = "~"
+		}
+		cmd := prefix + strings.ToUpper(zpl[i+1:i+3])
+		switch name := cmd[1:]; {
+		case name == "CC" || name == "CT":
+			if i+3 >= len(zpl) {
+				return fmt.Errorf("missing prefix of %s at offset %d", cmd, i)
+			}
+			if name == "CC" {
+				caret = zpl[i+3]
+			} else {
+				tilde = zpl[i+3]
+			}
+			i += 3
+			continue
+		case inField && cmd != "^FS":
+			return fmt.Errorf("field data not closed by ^FS before %s at offset %d", cmd, i)
+		}
+		switch cmd {
+		case "^XA":
+			if inLabel {
+				return fmt.Errorf("^XA at offset %d inside of a label", i)
+			}
+			inLabel = true
+		case "^XZ":
+			if !inLabel {
+				return fmt.Errorf("^XZ at offset %d without ^XA", i)
+			}
+			inLabel = false
+		case "^FD", "^FV":
+			inField = true
+		case "^FS":
+			inField = false
+		case "^GF":
+			// binary graphic fields may contain any byte, skip their data
+			n, err := skipGraphicField(zpl[i+3:])
+			if err != nil {
+				return fmt.Errorf("invalid ^GF at offset %d: %v", i, err)
+			}
+			i += n
+		}
+		i += 2
+	}
+	if inField {
+		return fmt.Errorf("field data not closed by ^FS")
+	}
+	if inLabel {
+		return fmt.Errorf("label not closed by ^XZ")
+	}
+	return nil
+}
+
+// skipGraphicField returns the number of bytes the parameters and data of a
+// binary ^GF take, ASCII data is checked as every other command
+func skipGraphicField(params string) (int, error) {
+	if !strings.HasPrefix(params, "B") {
+		return 0, nil
+	}
+	parts := strings.SplitN(params, ",", 5)
+	if len(parts) < 5 {
+		return 0, fmt.Errorf("missing parameters")
+	}
+	size, err := strconv.Atoi(parts[1])
+	if err != nil || size < 0 {
+		return 0, fmt.Errorf("invalid byte count %q", parts[1])
+	}
+	// data of ConvertToGraphicField starts after a line break
+	data := parts[4]
+	skip := len(params) - len(data)
+	if strings.HasPrefix(data, "\n") {
+		skip++
+	}
+	if skip > len(params) || size > len(params)-skip {
+		return 0, fmt.Errorf("data shorter than %d bytes", size)
+	}
+	return skip + size, nil
+}
@@ -0,0 +1,85 @@
+package zplgfa
+
+import (
+	"image"
+	"strings"
+	"testing"
+)
+
+func Test_RenderTemplate(t *testing.T) {
+	logo := whiteImage(16, 8)
+	fillRect(logo, image.Rect(0, 0, 8, 8))
+	data := map[string]interface{}{
+		"Logo":     logo,
+		"Name":     "Jane ^Doe~",
+		"Tracking": "PK123456",
+	}
+	const tmpl = "^XA\n" +
+		"^FO{{mm 5}},{{mm 5}}{{gf .Logo}}^FS\n" +
+		"^FO{{mm 5}},{{mm 10}}^A0N,30,30{{zplEscape .Name}}^FS\n" +
+		"^FO{{mm 5}},{{mm 20}}{{code128 .Tracking 100}}\n" +
+		"^FO{{mm 50}},{{mm 20}}{{qrcode .Tracking 4}}\n" +
+		"^XZ\n"
+
+	zpl, err := RenderTemplate(tmpl, data, 203)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"^FO40,40^GFA,",
+		"^FO40,80^A0N,30,30^FH^FDJane _5EDoe_7E^FS",
+		"^FO40,160^BCN,100,Y,N,N^FDPK123456^FS",
+		"^FO400,160^BQN,2,4^FDQA,PK123456^FS",
+	} {
+		if !strings.Contains(zpl, want) {
+			t.Errorf("expected %q in:\n%s", want, zpl)
+		}
+	}
+
+	if _, err := RenderTemplate("^XA{{gf 42}}^XZ", data, 203); err == nil {
+		t.Error("expected an error for an invalid image source")
+	}
+	if _, err := RenderTemplate("^XA{{gf \"does-not-exist.png\"}}^XZ", data, 203); err == nil {
+		t.Error("expected an error for a missing image file")
+	}
+}
+
+func Test_ValidateZPL(t *testing.T) {
+	img, _, err := image.Decode(strings.NewReader(string(imgPNG)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	valid := []string{
+		ConvertToZPL(img, ASCII),
+		ConvertToZPL(img, Binary),
+		ConvertToZPL(img, CompressedASCII),
+		"~JA^XA^FO0,0^A0N,20,20^FDtext^FS^XZ^XA^XZ",
+		"^XA^CT#^CC||FDa^b~c|CC^^CT~^FS^XZ",
+		"^XA^CT|^FDa~b^CT~^FS~JA^XZ",
+	}
+	for i, zpl := range valid {
+		if err := ValidateZPL(zpl); err != nil {
+			t.Errorf("valid ZPL %d: %v", i, err)
+		}
+	}
+
+	invalid := []string{
+		"^XA^FDtext^FS",
+		"^FDtext^FS^XZ",
+		"^XA^XA^XZ",
+		"^XA^FDtext^XZ",
+		"^XA^FDa^b^FS^XZ",
+		"^XA^FDa~b^FS^XZ",
+		"^XA^GFB,100,100,10,\nabc^FS^XZ",
+		"^XA^GFB,-100,10,1,\nab^FS^XZ",
+		"^XA" + strings.Repeat("^FO0,0", 40) + "^GFB,-100,10,1,\nab^FS^XZ",
+		"^XA^X",
+		"^XA^CC|^FDa^FS^XZ",
+		"^XA^CC||FDa|b|FS|XZ",
+	}
+	for _, zpl := range invalid {
+		if err := ValidateZPL(zpl); err == nil {
+			t.Errorf("expected %q to be invalid", zpl)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package zplgfa
+
+import (
+	"image"
+	"image/draw"
+	"strings"
+	"unicode"
+
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+)
+
+// TextAlign is a type to select the horizontal alignment of text lines
+type TextAlign int
+
+const (
+	// AlignLeft aligns the lines at the left edge of the text box
+	AlignLeft TextAlign = iota
+	// AlignCenter centers the lines within the text box
+	AlignCenter
+	// AlignRight aligns the lines at the right edge of the text box
+	AlignRight
+)
+
+// TextOptions controls how RenderText lays out text
+type TextOptions struct {
+	// Size is the font size in points
+	Size float64
+	// DPI is the resolution of the printer, 203 if not set. With a DPI of 72
+	// the Size is given in dots.
+	DPI float64
+	// Width is the width of the text box in dots, longer lines are wrapped.
+	// Without a width every line is as wide as its text.
+	Width int
+	// Align is the horizontal alignment of the lines within the text box
+	Align TextAlign
+	// LineSpacing is the distance of the lines as a multiple of the line
+	// height of the font, 1 if not set
+	LineSpacing float64
+}
+
+// wrapText splits text into lines which fit into width (if width > 0).
+// Lines are wrapped at white space, words wider than the box (e.g. CJK text
+// without spaces) are broken between characters.
+func wrapText(face font.Face, text string, width int) []string {
+	fits := func(s string) bool {
+		return width <= 0 || font.MeasureString(face, s).Ceil() <= width
+	}
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.FieldsFunc(paragraph, unicode.IsSpace) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if fits(candidate) {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+			}
+			line = ""
+			for _, r := range word {
+				if line != "" && !fits(line+string(r)) {
+					lines = append(lines, line)
+					line = ""
+				}
+				line += string(r)
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// RenderText renders text with the given TrueType or OpenType font into a
+// black and white image, ready to be converted to a Graphic Field. This way
+// fonts and scripts the printer doesn't know can be printed pixel-exactly.
+// Glyphs are placed one after the other, scripts which need complex shaping
+// (like Arabic) have to be passed in their shaped presentation forms.
+func RenderText(fontData []byte, text string, opts TextOptions) (image.Image, error) {
+	f, err := opentype.Parse(fontData)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DPI == 0 {
+		opts.DPI = 203
+	}
+	if opts.LineSpacing == 0 {
+		opts.LineSpacing = 1
+	}
+	face, err := opentype.NewFace(f, &opentype.FaceOptions{
+		Size:    opts.Size,
+		DPI:     opts.DPI,
+		Hinting: font.HintingFull,
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer face.Close()
+
+	lines := wrapText(face, text, opts.Width)
+	width := opts.Width
+	if width <= 0 {
+		for _, line := range lines {
+			if w := font.MeasureString(face, line).Ceil(); w > width {
+				width = w
+			}
+		}
+	}
+	metrics := face.Metrics()
+	lineHeight := int(float64(metrics.Height.Ceil())*opts.LineSpacing + 0.5)
+	height := lineHeight*(len(lines)-1) + metrics.Ascent.Ceil() + metrics.Descent.Ceil()
+
+	dst := image.NewGray(image.Rect(0, 0, width, height))
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	drawer := font.Drawer{Dst: dst, Src: image.Black, Face: face}
+	for i, line := range lines {
+		x := 0
+		switch opts.Align {
+		case AlignCenter:
+			x = (width - font.MeasureString(face, line).Ceil()) / 2
+		case AlignRight:
+			x = width - font.MeasureString(face, line).Ceil()
+		}
+		drawer.Dot = fixed.P(x, metrics.Ascent.Ceil()+i*lineHeight)
+		drawer.DrawString(line)
+	}
+	return dst, nil
+}
+
+// ConvertTextToGraphicField renders text via RenderText and converts it to a
+// ZPL Graphic Field of the given type
+func ConvertTextToGraphicField(fontData []byte, text string, opts TextOptions, graphicType GraphicType) (string, error) {
+	img, err := RenderText(fontData, text, opts)
+	if err != nil {
+		return "", err
+	}
+	return ConvertToGraphicField(img, graphicType), nil
+}
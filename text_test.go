@@ -0,0 +1,73 @@
+package zplgfa
+
+import (
+	"image"
+	"strings"
+	"testing"
+
+	"golang.org/x/image/font/gofont/goregular"
+)
+
+// inkColumns returns the first and last column of img containing black dots
+func inkColumns(img image.Image) (int, int) {
+	crop := CropBounds(img)
+	return crop.Min.X, crop.Max.X
+}
+
+func Test_RenderText(t *testing.T) {
+	opts := TextOptions{Size: 12, DPI: 203}
+	single, err := RenderText(goregular.TTF, "Hello World", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CropBounds(single).Empty() {
+		t.Fatal("expected rendered text to contain black dots")
+	}
+
+	opts.Width = single.Bounds().Dx() * 3 / 4
+	wrapped, err := RenderText(goregular.TTF, "Hello World", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if wrapped.Bounds().Dx() != opts.Width {
+		t.Fatalf("expected text box width of %d, got %d", opts.Width, wrapped.Bounds().Dx())
+	}
+	if wrapped.Bounds().Dy() <= single.Bounds().Dy() {
+		t.Fatalf("expected text to be wrapped into two lines")
+	}
+
+	opts.Width = 400
+	opts.Align = AlignLeft
+	left, _ := RenderText(goregular.TTF, "abc", opts)
+	opts.Align = AlignRight
+	right, _ := RenderText(goregular.TTF, "abc", opts)
+	opts.Align = AlignCenter
+	center, _ := RenderText(goregular.TTF, "abc", opts)
+	l, _ := inkColumns(left)
+	c, _ := inkColumns(center)
+	_, r := inkColumns(right)
+	if l > 10 || r < 390 || c < 150 || c > 250 {
+		t.Fatalf("unexpected alignment: left %d, center %d, right %d", l, c, r)
+	}
+
+	if _, err := RenderText([]byte("no font"), "abc", opts); err == nil {
+		t.Fatal("expected an error for invalid font data")
+	}
+}
+
+func Test_wrapText(t *testing.T) {
+	img, _ := RenderText(goregular.TTF, "WWWWWWWWWWWW", TextOptions{Size: 10, Width: 60})
+	if img.Bounds().Dx() != 60 || img.Bounds().Dy() < 40 {
+		t.Fatalf("expected long words to be broken into several lines, got %v", img.Bounds())
+	}
+}
+
+func Test_ConvertTextToGraphicField(t *testing.T) {
+	gf, err := ConvertTextToGraphicField(goregular.TTF, "ZPL", TextOptions{Size: 10, Width: 100}, CompressedASCII)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(gf, "^GFA,") {
+		t.Fatalf("unexpected graphic field: %s", gf)
+	}
+}
@@ -0,0 +1,77 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// Tile is a part of an image, encoded as a Graphic Field of its own
+type Tile struct {
+	// X and Y are the offset of the tile relative to the origin of the image
+	X, Y int
+	// GraphicField holds the ^GF element of the tile
+	GraphicField string
+}
+
+// tileSize calculates the size of the tiles an image of the given size needs
+// to be split into, so no Graphic Field exceeds maxBytes of (uncompressed)
+// graphic data. Tile widths are kept at multiples of 8 dots to not shift the
+// bit alignment of the rows.
+func tileSize(size image.Point, maxBytes int) image.Point {
+	if maxBytes <= 0 {
+		return size
+	}
+	// a row needs (size.X+7)/8 bytes, see fieldWidth
+	width := size.X
+	if fieldWidth(size) > maxBytes {
+		width = 8 * maxBytes
+	}
+	height := maxBytes / fieldWidth(image.Pt(width, 1))
+	if height < 1 {
+		height = 1
+	}
+	if height > size.Y {
+		height = size.Y
+	}
+	return image.Pt(width, height)
+}
+
+// ConvertToGraphicFieldTiles splits source into tiles and converts each of
+// them to a Graphic Field with at most maxBytes of graphic data. Images are cut
+// into horizontal bands, if a single row is already bigger than maxBytes the
+// bands are split into a grid. Blank tiles are left out, a maxBytes value of 0
+// or less disables the splitting.
+func ConvertToGraphicFieldTiles(source image.Image, graphicType GraphicType, maxBytes int) []Tile {
+	bounds := source.Bounds()
+	size := tileSize(bounds.Size(), maxBytes)
+	var tiles []Tile
+	for y := bounds.Min.Y; y < bounds.Max.Y; y += size.Y {
+		for x := bounds.Min.X; x < bounds.Max.X; x += size.X {
+			r := image.Rect(x, y, x+size.X, y+size.Y).Intersect(bounds)
+			tile := subImage(source, r)
+			if CropBounds(tile).Empty() {
+				continue
+			}
+			tiles = append(tiles, Tile{
+				X:            x - bounds.Min.X,
+				Y:            y - bounds.Min.Y,
+				GraphicField: ConvertToGraphicField(tile, graphicType),
+			})
+		}
+	}
+	return tiles
+}
+
+// ConvertToZPLTiled works like ConvertToZPL, but splits the image into several
+// Graphic Fields of at most maxBytes of graphic data each. Every tile gets its
+// own Field Origin, so the printed result matches the one of a single field.
+func ConvertToZPLTiled(img image.Image, graphicType GraphicType, maxBytes int) string {
+	var sb strings.Builder
+	sb.WriteString("^XA,^FS\n")
+	for _, tile := range ConvertToGraphicFieldTiles(img, graphicType, maxBytes) {
+		fmt.Fprintf(&sb, "^FO%d,%d\n%s^FS\n", tile.X, tile.Y, tile.GraphicField)
+	}
+	sb.WriteString("^XZ\n")
+	return sb.String()
+}
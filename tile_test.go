@@ -0,0 +1,81 @@
+package zplgfa
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"strings"
+	"testing"
+)
+
+// paintASCIIField sets the dots of an ASCII encoded graphic field at the
+// offset x, y in canvas and returns the total byte count of its header
+func paintASCIIField(t *testing.T, canvas map[image.Point]bool, x, y int, gf string) int {
+	t.Helper()
+	lines := strings.Split(strings.TrimSpace(gf), "\n")
+	var total, width int
+	if _, err := fmt.Sscanf(lines[0], "^GFA,%d,%d,%d,", new(int), &total, &width); err != nil {
+		t.Fatalf("unexpected graphic field header %q: %v", lines[0], err)
+	}
+	for row, line := range lines[1:] {
+		data, err := hex.DecodeString(line)
+		if err != nil {
+			t.Fatalf("invalid row %q: %v", line, err)
+		}
+		for i, b := range data {
+			for bit := 0; bit < 8; bit++ {
+				if b&(0x80>>bit) != 0 {
+					canvas[image.Pt(x+i*8+bit, y+row)] = true
+				}
+			}
+		}
+	}
+	return total
+}
+
+func Test_ConvertToGraphicFieldTiles(t *testing.T) {
+	img, _, err := image.Decode(bytes.NewReader(imgPNG))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[image.Point]bool{}
+	paintASCIIField(t, want, 0, 0, ConvertToGraphicField(img, ASCII))
+
+	for _, maxBytes := range []int{4096, 500, 20} {
+		t.Run(fmt.Sprint(maxBytes), func(t *testing.T) {
+			tiles := ConvertToGraphicFieldTiles(img, ASCII, maxBytes)
+			if len(tiles) < 2 {
+				t.Fatalf("expected image to be split, got %d tiles", len(tiles))
+			}
+			got := map[image.Point]bool{}
+			for _, tile := range tiles {
+				if total := paintASCIIField(t, got, tile.X, tile.Y, tile.GraphicField); total > maxBytes {
+					t.Fatalf("tile at %d,%d has %d bytes, limit is %d", tile.X, tile.Y, total, maxBytes)
+				}
+			}
+			if len(got) != len(want) {
+				t.Fatalf("tiles contain %d dots, want %d", len(got), len(want))
+			}
+			for p := range want {
+				if !got[p] {
+					t.Fatalf("dot at %v missing in tiled output", p)
+				}
+			}
+		})
+	}
+}
+
+func Test_ConvertToZPLTiled(t *testing.T) {
+	img := whiteImage(16, 16)
+	fillRect(img, image.Rect(0, 0, 16, 4))
+	fillRect(img, image.Rect(0, 12, 16, 16))
+
+	zpl := ConvertToZPLTiled(img, ASCII, 12)
+	if n := strings.Count(zpl, "^GF"); n != 2 {
+		t.Fatalf("expected blank band to be skipped, got %d fields:\n%s", n, zpl)
+	}
+	if !strings.Contains(zpl, "^FO0,0\n") || !strings.Contains(zpl, "^FO0,12\n") {
+		t.Fatalf("unexpected field origins:\n%s", zpl)
+	}
+}
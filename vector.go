@@ -0,0 +1,124 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"strings"
+)
+
+// growBox returns the biggest filled rectangle starting at the top left dot
+// x, y, either by first following the row and then extending it downwards or
+// by first following the column and then extending it to the right
+func (bm *bitmap) growBox(x, y int) image.Rectangle {
+	w := 0
+	for bm.black(x+w, y) {
+		w++
+	}
+	h := 1
+	for bm.rowFilled(x, x+w, y+h) {
+		h++
+	}
+	rowFirst := image.Rect(x, y, x+w, y+h)
+
+	h = 0
+	for bm.black(x, y+h) {
+		h++
+	}
+	w = 1
+	for bm.columnFilled(x+w, y, y+h) {
+		w++
+	}
+	columnFirst := image.Rect(x, y, x+w, y+h)
+
+	if columnFirst.Dx()*columnFirst.Dy() > rowFirst.Dx()*rowFirst.Dy() {
+		return columnFirst
+	}
+	return rowFirst
+}
+
+func (bm *bitmap) rowFilled(minX, maxX, y int) bool {
+	for x := minX; x < maxX; x++ {
+		if !bm.black(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+func (bm *bitmap) columnFilled(x, minY, maxY int) bool {
+	for y := minY; y < maxY; y++ {
+		if !bm.black(x, y) {
+			return false
+		}
+	}
+	return true
+}
+
+// FindBoxes searches img for solid black, axis-aligned rectangles which are at
+// least minLength dots wide or high. This finds filled boxes as well as long
+// horizontal and vertical lines. The rectangles are relative to the origin of
+// img and may overlap.
+func FindBoxes(img image.Image, minLength int) []image.Rectangle {
+	return bitmapFromImage(img).findBoxes(minLength)
+}
+
+func (bm *bitmap) findBoxes(minLength int) []image.Rectangle {
+	if minLength < 1 {
+		minLength = 1
+	}
+	covered := newBitmap(bm.width, bm.height)
+	var boxes []image.Rectangle
+	for y := 0; y < bm.height; y++ {
+		for x := 0; x < bm.width; x++ {
+			if !bm.black(x, y) || covered.black(x, y) {
+				continue
+			}
+			box := bm.growBox(x, y)
+			if box.Dx() < minLength && box.Dy() < minLength {
+				continue
+			}
+			for by := box.Min.Y; by < box.Max.Y; by++ {
+				for bx := box.Min.X; bx < box.Max.X; bx++ {
+					covered.set(bx, by, true)
+				}
+			}
+			boxes = append(boxes, box)
+		}
+	}
+	return boxes
+}
+
+// GraphicBox returns a ZPL ^GB (Graphic Box) element drawing the filled rectangle r
+func GraphicBox(r image.Rectangle) string {
+	thickness := r.Dx()
+	if r.Dy() < thickness {
+		thickness = r.Dy()
+	}
+	return fmt.Sprintf("^FO%d,%d^GB%d,%d,%d^FS\n", r.Min.X, r.Min.Y, r.Dx(), r.Dy(), thickness)
+}
+
+// ConvertToZPLVectorized works like ConvertToZPL, but draws solid rectangles
+// and lines of at least minLength dots with ^GB (Graphic Box) elements. Only
+// the remaining dots are encoded in a (cropped) Graphic Field, which shrinks
+// the payload and prints sharper edges.
+func ConvertToZPLVectorized(img image.Image, graphicType GraphicType, minLength int) string {
+	bm := bitmapFromImage(img)
+	rest := bm.clone()
+
+	var sb strings.Builder
+	sb.WriteString("^XA,^FS\n")
+	for _, box := range bm.findBoxes(minLength) {
+		sb.WriteString(GraphicBox(box))
+		for y := box.Min.Y; y < box.Max.Y; y++ {
+			for x := box.Min.X; x < box.Max.X; x++ {
+				rest.set(x, y, false)
+			}
+		}
+	}
+	if crop := CropBounds(rest); !crop.Empty() {
+		gf := ConvertToGraphicField(subImage(rest, crop), graphicType)
+		fmt.Fprintf(&sb, "^FO%d,%d\n%s^FS\n", crop.Min.X, crop.Min.Y, gf)
+	}
+	sb.WriteString("^XZ\n")
+	return sb.String()
+}
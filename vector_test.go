@@ -0,0 +1,64 @@
+package zplgfa
+
+import (
+	"fmt"
+	"image"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func Test_FindBoxes(t *testing.T) {
+	img := whiteImage(64, 48)
+	fillRect(img, image.Rect(0, 0, 64, 2))    // top border
+	fillRect(img, image.Rect(0, 2, 2, 48))    // left border
+	fillRect(img, image.Rect(20, 10, 40, 30)) // solid block
+	fillRect(img, image.Rect(50, 20, 53, 23)) // small glyph
+
+	boxes := FindBoxes(img, 16)
+	want := []image.Rectangle{
+		image.Rect(0, 0, 64, 2),
+		image.Rect(0, 2, 2, 48),
+		image.Rect(20, 10, 40, 30),
+	}
+	if fmt.Sprint(boxes) != fmt.Sprint(want) {
+		t.Fatalf("FindBoxes = %v, want %v", boxes, want)
+	}
+}
+
+func Test_ConvertToZPLVectorized(t *testing.T) {
+	img := whiteImage(64, 48)
+	fillRect(img, image.Rect(0, 0, 64, 2))
+	fillRect(img, image.Rect(20, 10, 40, 30))
+	fillRect(img, image.Rect(50, 20, 53, 23))
+
+	zpl := ConvertToZPLVectorized(img, ASCII, 16)
+	for _, gb := range []string{"^FO0,0^GB64,2,2^FS", "^FO20,10^GB20,20,20^FS"} {
+		if !strings.Contains(zpl, gb) {
+			t.Fatalf("expected %s in:\n%s", gb, zpl)
+		}
+	}
+
+	// the leftover glyph ends up in a cropped graphic field
+	m := regexp.MustCompile(`\^FO(\d+),(\d+)\n(\^GF[^^]*)`).FindStringSubmatch(zpl)
+	if m == nil {
+		t.Fatalf("expected a graphic field for the leftover dots:\n%s", zpl)
+	}
+	var x, y int
+	fmt.Sscan(m[1], &x)
+	fmt.Sscan(m[2], &y)
+	dots := map[image.Point]bool{}
+	paintASCIIField(t, dots, x, y, m[3])
+	if len(dots) != 9 {
+		t.Fatalf("expected the 9 dots of the glyph, got %v", dots)
+	}
+	for p := range dots {
+		if !p.In(image.Rect(50, 20, 53, 23)) {
+			t.Fatalf("unexpected dot at %v", p)
+		}
+	}
+
+	if zpl := ConvertToZPLVectorized(img, ASCII, 2); strings.Contains(zpl, "^GF") {
+		t.Fatalf("expected all dots to be vectorized:\n%s", zpl)
+	}
+}
@@ -64,12 +64,14 @@ func rgbaFromColor(c color.Color) rgba {
 	return rgba{r, g, b, a}
 }
 
+// shortcircuit skips flattening opaque white and black pixels. Transparent
+// pixels are flattened onto white, so they aren't printed whatever their color.
 func shortcircuit(input rgba) (color.Gray16, bool) {
 	r, g, b, a := input.RGBA()
 	if whiteish(r) && whiteish(g) && whiteish(b) && whiteish(a) {
 		return shortWhite, true
 	}
-	if blackish(r) && blackish(g) && blackish(b) && blackish(a) {
+	if blackish(r) && blackish(g) && blackish(b) && whiteish(a) {
 		return shortBlack, true
 	}
 	return color.Gray16{}, false
@@ -79,10 +81,10 @@ func shortcircuit(input rgba) (color.Gray16, bool) {
 // Not really needed as ConvertToGraphicField already does this internally
 // to avoid looping through image (and doing image.At calls) twice
 func FlattenImage(source image.Image) *image.Gray16 {
-	size := source.Bounds().Size()
-	target := image.NewGray16(source.Bounds())
-	for y := 0; y < size.Y; y++ {
-		for x := 0; x < size.X; x++ {
+	bounds := source.Bounds()
+	target := image.NewGray16(bounds)
+	for y := bounds.Min.Y; y < bounds.Max.Y; y++ {
+		for x := bounds.Min.X; x < bounds.Max.X; x++ {
 			p := source.At(x, y)
 			rgba := rgbaFromColor(p)
 			flat, ok := shortcircuit(rgba)
@@ -116,6 +118,16 @@ func flatten(input rgba) color.Gray16 {
 	return gray16Model(conv(r), conv(g), conv(b))
 }
 
+// isBlack reports whether a pixel ends up as a printed (black) dot
+func isBlack(r, g, b, a uint32) bool {
+	rgba := rgba{r, g, b, a}
+	lum, ok := shortcircuit(rgba)
+	if !ok {
+		lum = flatten(rgba)
+	}
+	return lum.Y < math.MaxUint16/2
+}
+
 func writeRepeatCode(dst io.Writer, repeatCount int, char rune) int {
 	n := 0
 	if repeatCount > 419 {
@@ -185,6 +197,12 @@ func CompressASCII(dst io.Writer, in string) {
 	}
 }
 
+// fieldWidth returns the number of bytes per row of a Graphic Field, a
+// trailing partial byte included
+func fieldWidth(size image.Point) int {
+	return (size.X + 7) / 8
+}
+
 func mustWrite(dst io.Writer, s []byte) int {
 	n, err := dst.Write(s)
 	if err != nil {
@@ -197,13 +215,20 @@ func mustWrite(dst io.Writer, s []byte) int {
 // The ZPL ^GF (Graphic Field) supports various data formats, this package supports the
 // normal ASCII encoded, as well as a RLE compressed ASCII format. It also supports the
 // Binary Graphic Field format. The encoding can be chosen by the second argument.
+// Every row takes (width+7)/8 bytes, the dots of a partial last byte included, and
+// transparent pixels are never printed, whatever their color.
 func ConvertToGraphicField(source image.Image, graphicType GraphicType) string {
-	size := source.Bounds().Size()
-	width := size.X / 8
+	data, width, height := encodeGraphic(source, graphicType)
+	return fmt.Sprintf("^GF%s,%d,%d,%d,\n", graphicType.String(), data.Len(), width*height, width) + data.String()
+}
+
+// encodeGraphic encodes the dots of source in the given format and returns the
+// data together with the number of bytes per row and the number of rows
+func encodeGraphic(source image.Image, graphicType GraphicType) (*bytes.Buffer, int, int) {
+	bounds := source.Bounds()
+	size := bounds.Size()
+	width := fieldWidth(size)
 	height := size.Y
-	if size.Y%8 != 0 {
-		width = width + 1
-	}
 
 	dst := bytes.NewBuffer(make([]byte, 0, 8*1024))
 	var compressionBuf *bytes.Buffer
@@ -244,13 +269,7 @@ func ConvertToGraphicField(source image.Image, graphicType GraphicType) string {
 		currentByte := line[lineIndex]
 		for x := 0; x < size.X; x++ {
 			index = index + 1
-			r, g, b, a := pxRGBA(x, y)
-			rgba := rgba{r, g, b, a}
-			lum, ok := shortcircuit(rgba)
-			if !ok {
-				lum = flatten(rgba)
-			}
-			if lum.Y < math.MaxUint16/2 {
+			if isBlack(pxRGBA(bounds.Min.X+x, bounds.Min.Y+y)) {
 				currentByte = currentByte | (1 << (8 - index))
 			}
 			if index >= 8 {
@@ -262,6 +281,10 @@ func ConvertToGraphicField(source image.Image, graphicType GraphicType) string {
 				index = 0
 			}
 		}
+		if index > 0 {
+			// the remaining dots of a partial byte
+			line[lineIndex] = currentByte
+		}
 
 		hexstr := strings.ToUpper(hex.EncodeToString(line))
 
@@ -282,5 +305,5 @@ func ConvertToGraphicField(source image.Image, graphicType GraphicType) string {
 		}
 	}
 
-	return fmt.Sprintf("^GF%s,%d,%d,%d,\n", graphicType.String(), dst.Len(), width*height, width) + dst.String()
+	return dst, width, height
 }
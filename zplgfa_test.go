@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"image"
+	"image/color"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
@@ -29,6 +30,29 @@ func Test_CompressASCII(t *testing.T) {
 	}
 }
 
+func Test_ConvertOddWidth(t *testing.T) {
+	// 12 dots need a full and a partial byte per row, also with a height
+	// that is a multiple of 8
+	img := image.NewNRGBA(image.Rect(0, 0, 12, 8))
+	for x := 0; x < 12; x++ {
+		img.Set(x, 0, color.Black)
+	}
+	if gf := ConvertToGraphicField(img, ASCII); !strings.HasPrefix(gf, "^GFA,40,16,2,\nFFF0\n0000\n") {
+		t.Fatalf("expected all 12 dots of the first row, got %q", gf)
+	}
+}
+
+func Test_ConvertTransparent(t *testing.T) {
+	img := image.NewNRGBA(image.Rect(0, 0, 16, 2))
+	for x := 8; x < 16; x++ {
+		img.Set(x, 1, color.Black)
+	}
+	// the other dots are transparent black
+	if gf := ConvertToGraphicField(img, ASCII); gf != "^GFA,10,4,2,\n0000\n00FF\n" {
+		t.Fatalf("expected only the opaque dots to be printed, got %q", gf)
+	}
+}
+
 func Test_ConvertToZPL(t *testing.T) {
 	f, err := os.Open("./tests/tests.json")
 	if err != nil {